@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"service-info-aggregator/internal/config"
+	"service-info-aggregator/internal/handler/health"
+	"service-info-aggregator/internal/messaging"
+	"service-info-aggregator/internal/messaging/kafka"
+)
+
+const (
+	bootstrapReadiness  = "cache-bootstrap"
+	maxBootstrapBackoff = time.Minute
+)
+
+type stateReplayer interface {
+	Run(ctx context.Context, opts kafka.ReplayOptions, handler messaging.MessageHandler) (int, error)
+}
+
+// bootstrapUntilReady keeps the instance not ready until bootstrap succeeds:
+// serving before the cache is loaded would answer from a cold cache. Failed
+// attempts are retried with a backoff doubling from backoff up to a minute.
+func bootstrapUntilReady(ctx context.Context, readiness *health.Readiness, topic string,
+	backoff time.Duration, bootstrap func(ctx context.Context) error) {
+	readiness.NotReady(bootstrapReadiness, "loading "+topic)
+	for {
+		err := bootstrap(ctx)
+		if err == nil {
+			readiness.Ready(bootstrapReadiness)
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Error("cache bootstrap failed, retrying", "topic", topic, "error", err, "backoff", backoff)
+		readiness.NotReady(bootstrapReadiness, "loading "+topic+" failed: "+err.Error())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBootstrapBackoff)
+	}
+}
+
+// bootstrapFromKafka replays the state topic with a replayer of its own.
+func bootstrapFromKafka(cfg *config.KafkaConfig, serializer messaging.EventSerializer, router *messaging.EventRouter) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		replayer, err := kafka.NewReplayer(cfg, cfg.ClientID+"-bootstrap")
+		if err != nil {
+			return err
+		}
+		defer replayer.Close()
+
+		return bootstrapCache(ctx, replayer, cfg.StateTopic, serializer, router)
+	}
+}
+
+// bootstrapCache reads the compacted state topic to its end and applies the
+// latest event of every key to the cache.
+func bootstrapCache(ctx context.Context, replayer stateReplayer, topic string, serializer messaging.EventSerializer, router *messaging.EventRouter) error {
+	started := time.Now()
+	applied := 0
+
+	read, err := replayer.Run(ctx, kafka.ReplayOptions{Topic: topic, FromOffset: -1}, func(ctx context.Context, msg *messaging.Message) error {
+		// Tombstones of deleted keys.
+		if len(msg.Value) == 0 {
+			return nil
+		}
+
+		event, err := serializer.Deserialize(ctx, msg)
+		if err != nil {
+			slog.Warn("skipping undecodable state message", "key", string(msg.Key), "error", err)
+			return nil
+		}
+		if err := router.Route(ctx, event); err != nil {
+			slog.Warn("failed to apply state event", "key", string(msg.Key), "error", err)
+			return nil
+		}
+		applied++
+		return nil
+	})
+
+	slog.Info("cache bootstrap finished",
+		"topic", topic,
+		"read", read,
+		"applied", applied,
+		"duration", time.Since(started))
+	return err
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"service-info-aggregator/internal/handler/health"
+	"service-info-aggregator/internal/messaging"
+	"service-info-aggregator/internal/messaging/kafka"
+	"service-info-aggregator/internal/model/events"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeReplayer hands out msgs and returns once end is closed, the way the
+// replayer returns once it reached the end offsets.
+type fakeReplayer struct {
+	msgs []*messaging.Message
+	end  chan struct{}
+	opts kafka.ReplayOptions
+}
+
+func (r *fakeReplayer) Run(ctx context.Context, opts kafka.ReplayOptions, handler messaging.MessageHandler) (int, error) {
+	r.opts = opts
+	for _, msg := range r.msgs {
+		if err := handler(ctx, msg); err != nil {
+			return 0, err
+		}
+	}
+	<-r.end
+	return len(r.msgs), nil
+}
+
+type countingHandler struct {
+	mu       sync.Mutex
+	subjects []string
+}
+
+func (h *countingHandler) Type() string { return "weather" }
+
+func (h *countingHandler) Handle(ctx context.Context, event *events.CloudEvent) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subjects = append(h.subjects, event.Subject)
+	return nil
+}
+
+func (h *countingHandler) handled() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.subjects...)
+}
+
+func stateMessage(t *testing.T, subject string) *messaging.Message {
+	msg, err := messaging.EncodeCloudEvent("aggregator.state", &events.CloudEvent{
+		ID:          subject,
+		Source:      "/test",
+		SpecVersion: events.CloudEventsSpecVersion,
+		Type:        "weather",
+		Subject:     subject,
+		Time:        time.Now(),
+	}, messaging.EventModeStructured)
+	require.NoError(t, err)
+	return msg
+}
+
+func TestBootstrapUntilReady_NotReadyUntilStateTopicIsRead(t *testing.T) {
+	handler := &countingHandler{}
+	router := messaging.NewEventRouter(messaging.WithHandlers(handler))
+	serializer := messaging.NewJSONSerializer(messaging.EventModeStructured)
+	replayer := &fakeReplayer{
+		msgs: []*messaging.Message{
+			stateMessage(t, "Moscow"),
+			{Key: []byte("weather:Berlin")},                          // tombstone
+			{Key: []byte("weather:Paris"), Value: []byte("garbage")}, // undecodable
+			stateMessage(t, "London"),
+		},
+		end: make(chan struct{}),
+	}
+
+	readiness := health.NewReadiness()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		bootstrapUntilReady(context.Background(), readiness, "aggregator.state", time.Millisecond, func(ctx context.Context) error {
+			return bootstrapCache(ctx, replayer, "aggregator.state", serializer, router)
+		})
+	}()
+
+	require.Eventually(t, func() bool { return len(handler.handled()) == 2 }, time.Second, time.Millisecond)
+	ready, pending := readiness.Status()
+	assert.False(t, ready, "the end offsets are not reached yet")
+	assert.Equal(t, map[string]string{"cache-bootstrap": "loading aggregator.state"}, pending)
+
+	close(replayer.end)
+	<-done
+
+	ready, _ = readiness.Status()
+	assert.True(t, ready)
+	assert.Equal(t, []string{"Moscow", "London"}, handler.handled())
+	assert.Equal(t, kafka.ReplayOptions{Topic: "aggregator.state", FromOffset: -1}, replayer.opts)
+}
+
+func TestBootstrapUntilReady_RetriesFailuresAndStaysNotReady(t *testing.T) {
+	readiness := health.NewReadiness()
+	failing := make(chan struct{})
+	attempts := 0
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		bootstrapUntilReady(context.Background(), readiness, "aggregator.state", time.Millisecond, func(ctx context.Context) error {
+			attempts++
+			if attempts == 2 {
+				// Hold the second attempt until the failure was checked.
+				<-failing
+				return errors.New("broker unavailable")
+			}
+			if attempts < 3 {
+				return errors.New("broker unavailable")
+			}
+			return nil
+		})
+	}()
+
+	require.Eventually(t, func() bool {
+		_, pending := readiness.Status()
+		return pending["cache-bootstrap"] == "loading aggregator.state failed: broker unavailable"
+	}, time.Second, time.Millisecond)
+	ready, _ := readiness.Status()
+	assert.False(t, ready)
+
+	close(failing)
+	<-done
+
+	ready, _ = readiness.Status()
+	assert.True(t, ready)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestBootstrapUntilReady_StopsWithContext(t *testing.T) {
+	readiness := health.NewReadiness()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		bootstrapUntilReady(ctx, readiness, "aggregator.state", time.Hour, func(ctx context.Context) error {
+			return errors.New("broker unavailable")
+		})
+	}()
+
+	require.Eventually(t, func() bool {
+		_, pending := readiness.Status()
+		return pending["cache-bootstrap"] == "loading aggregator.state failed: broker unavailable"
+	}, time.Second, time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("bootstrap did not stop while backing off")
+	}
+	ready, _ := readiness.Status()
+	assert.False(t, ready)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
@@ -10,27 +11,55 @@ import (
 
 	"service-info-aggregator/internal/background"
 	"service-info-aggregator/internal/config"
+	"service-info-aggregator/internal/coordination"
+	"service-info-aggregator/internal/handler/admin"
+	"service-info-aggregator/internal/handler/health"
 	"service-info-aggregator/internal/handler/popular_data"
+	popularity2 "service-info-aggregator/internal/handler/popularity"
 	"service-info-aggregator/internal/handler/weather"
+	"service-info-aggregator/internal/messaging"
 	"service-info-aggregator/internal/messaging/kafka"
+	"service-info-aggregator/internal/messaging/memory"
+	"service-info-aggregator/internal/messaging/redisstream"
+	"service-info-aggregator/internal/messaging/schemaregistry"
+	"service-info-aggregator/internal/messaging/serde"
+	"service-info-aggregator/internal/model/dto"
 	"service-info-aggregator/internal/repository/aggregation_data"
 	postgresRepo "service-info-aggregator/internal/repository/popular_data"
+	"service-info-aggregator/internal/repository/popularity"
+	"service-info-aggregator/internal/repository/processed_events"
+	"service-info-aggregator/internal/repository/refresh_jobs"
+	"service-info-aggregator/internal/repository/scheduler_pauses"
+	"service-info-aggregator/internal/repository/scheduler_triggers"
 	"service-info-aggregator/internal/service/aggregation"
 	popular_data2 "service-info-aggregator/internal/service/popular_data"
 	"service-info-aggregator/internal/storage/postgres"
+	"service-info-aggregator/internal/tracing"
 
 	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		os.Exit(runReplay(os.Args[2:]))
+	}
+
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
 
+	slog.SetDefault(slog.New(tracing.NewLogHandler(slog.NewTextHandler(os.Stderr, nil))))
+
 	// --- Конфиги ---
 	pgCfg := config.NewPostgresConfig()
 	redisCfg := config.NewRedisConfig()
 	kafkaCfg := config.NewKafkaConfig()
+	messagingCfg := config.NewMessagingConfig()
+	schedulerCfg := config.NewSchedulerConfig()
+	popularityCfg := config.NewPopularityConfig()
+	jobQueueCfg := config.NewJobQueueConfig()
+	warmupCfg := config.NewWarmupConfig()
 
 	// --- Postgres ---
 	db, err := postgres.NewPostgresConnection(pgCfg)
@@ -38,6 +67,10 @@ func main() {
 		slog.Error("failed to connect postgres", "error", err)
 		return
 	}
+	if err := postgres.Migrate(ctx, db); err != nil {
+		slog.Error("failed to migrate postgres", "error", err)
+		return
+	}
 
 	// --- Redis ---
 	rdb := redis.NewClient(&redis.Options{
@@ -50,18 +83,33 @@ func main() {
 		slog.Error("failed to connect to redis", "error", err)
 		return
 	}
-	repo := aggregation_data.NewRedisRepository(rdb)
+	repo := aggregation_data.NewRedisRepository(rdb, coordination.TokenKey(schedulerCfg.LeaderKey))
+	processedEventsRepo := processed_events.NewRedisRepository(rdb)
+	popularityRepo := popularity.NewRedisRepository(rdb, popularityCfg.BucketDuration, popularityCfg.Buckets, popularityCfg.Decay)
 
-	// --- Kafka Producer ---
-	producer, err := kafka.NewKafkaProducer(
-		[]string{"127.0.0.1:9091", "127.0.0.1:9092", "127.0.0.1:9093"},
-		"aggregator-producer",
-	)
+	// --- Kafka Topics ---
+	if messagingCfg.Transport == "kafka" {
+		if err := ensureTopics(ctx, kafkaCfg); err != nil {
+			slog.Error("kafka topic check failed", "error", err)
+			return
+		}
+	}
+
+	// --- Message Broker ---
+	publisher, subscriber, err := newTransport(messagingCfg, kafkaCfg, rdb)
+	if err != nil {
+		slog.Error("failed to create message broker clients", "transport", messagingCfg.Transport, "error", err)
+		return
+	}
+	defer publisher.Close()
+	defer subscriber.Close()
+
+	// --- Event Serializer ---
+	serializer, err := newSerializer(messagingCfg, kafkaCfg.EventMode)
 	if err != nil {
-		slog.Error("failed to create kafka producer", "error", err)
+		slog.Error("failed to create event serializer", "error", err)
 		return
 	}
-	defer producer.Close()
 
 	// --- Popular Data Repository ---
 	popularDataRepository := postgresRepo.NewPopularDataRepository(db)
@@ -70,7 +118,12 @@ func main() {
 	popularDataService := popular_data2.NewPopularDataService(popularDataRepository)
 
 	// --- Сервис агрегирования ---
-	aggService := aggregation.NewAggregationService(producer, kafkaCfg.Topic)
+	stateTopic := ""
+	if messagingCfg.Transport == "kafka" {
+		stateTopic = kafkaCfg.StateTopic
+	}
+	topicRoutes := messaging.NewTopicRoutes(kafkaCfg.Topic, kafkaCfg.TopicRoutes)
+	aggService := aggregation.NewAggregationService(publisher, serializer, topicRoutes, stateTopic, kafkaCfg.EventSource)
 
 	// --- Popular Data Handler ---
 	popularDataHandler := popular_data.NewPopularDataHandler(popularDataService)
@@ -79,48 +132,173 @@ func main() {
 	weatherProvider := &aggregation.WeatherProvider{}
 
 	// --- Weather Handler (HTTP) ---
-	weatherHandler := weather.NewWeatherHandler(aggService, weatherProvider, repo, redisCfg.WeatherTTL)
+	weatherHandler := weather.NewWeatherHandler(aggService, weatherProvider, repo, popularityRepo, redisCfg.WeatherTTL)
+
+	// --- Popularity Handler ---
+	popularityHandler := popularity2.NewPopularityHandler(popularityRepo)
+
+	// --- Health Handler ---
+	readiness := health.NewReadiness()
+	healthHandler := health.NewHealthHandler(readiness)
 
 	mux := http.NewServeMux()
 
 	mux.Handle("/weather", weatherHandler)
 	mux.HandleFunc("/popular-data", popularDataHandler.HandleCollection)
 	mux.HandleFunc("/popular-data/", popularDataHandler.HandleItem)
+	mux.HandleFunc("/popularity/top", popularityHandler.HandleTop)
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", healthHandler.HandleLive)
+	mux.HandleFunc("/readyz", healthHandler.HandleReady)
 
-	// --- Kafka Event Handler ---
-	weatherEventHandler := kafka.NewWeatherEventHandler(repo, redisCfg.WeatherTTL)
-	eventRouter := kafka.NewEventRouter(weatherEventHandler)
+	// --- Consumer Monitoring ---
+	throughput := messaging.NewThroughputTracker()
+	adminOpts := []admin.Option{}
 
-	// --- Kafka Consumer ---
-	consumer, err := kafka.NewKafkaConsumer(
-		[]string{"127.0.0.1:9091", "127.0.0.1:9092", "127.0.0.1:9093"},
-		"aggregator-consumer",
-		eventRouter,
-	)
-	if err != nil {
-		slog.Error("failed to create kafka consumer", "error", err)
-		return
+	if consumer, ok := subscriber.(*kafka.KafkaConsumer); ok {
+		lagMonitor := kafka.NewLagMonitor(consumer, kafkaCfg.LagCheckInterval, kafkaCfg.MaxReadyLag, readiness)
+		go lagMonitor.Start(ctx)
+		adminOpts = append(adminOpts, admin.WithLagMonitor(lagMonitor))
+	}
+
+	// --- Event Handlers ---
+	eventRouter := newEventRouter(repo, redisCfg, messagingCfg, throughput.Middleware)
+	// The claim outlives a few handler timeouts; a claim left by a crash
+	// expires after it and the redelivered event is handled again.
+	dispatcher := messaging.NewDispatcher(serializer, eventRouter, processedEventsRepo, 3*messagingCfg.HandlerTimeout, redisCfg.EventDedupTTL)
+
+	// --- Прогрев кэша из compacted топика ---
+	if stateTopic != "" && kafkaCfg.StateBootstrap {
+		readiness.NotReady(bootstrapReadiness, "loading "+stateTopic)
+		// The state topic holds events of former leaderships, so they are
+		// applied without fencing.
+		bootstrapRouter := newEventRouter(aggregation_data.NewRedisRepository(rdb, ""), redisCfg, messagingCfg)
+		go bootstrapUntilReady(ctx, readiness, stateTopic, time.Second, bootstrapFromKafka(kafkaCfg, serializer, bootstrapRouter))
 	}
-	defer consumer.Close()
 
 	// --- Scheduler ---
-	scheduler := background.NewPriorityScheduler(popularDataService, aggService, 30*time.Second)
+	scheduler := background.NewPriorityScheduler(popularDataService, aggService, schedulerCfg, weatherProvider)
+	pauseStore := scheduler_pauses.NewRedisRepository(rdb, schedulerCfg.PauseKey)
+	scheduler.SetPauseStore(pauseStore)
+	scheduler.SetTriggerStore(scheduler_triggers.NewRedisRepository(rdb, schedulerCfg.TriggerKey))
 
-	go func() {
-		scheduler.Start(ctx)
-	}()
+	warmer := background.NewCacheWarmer(popularDataService, aggService, warmupCfg, weatherProvider)
+	warmer.SetCache(repo)
+
+	// --- Persistent refresh job queue ---
+	if jobQueueCfg.Enabled {
+		refreshJobRepository := refresh_jobs.NewRefreshJobRepository(db)
+		scheduler.SetQueue(refreshJobRepository)
+		warmer.SetQueue(refreshJobRepository)
+		jobWorker := background.NewJobWorker(refreshJobRepository, aggService, jobQueueCfg, schedulerCfg.InstanceID, weatherProvider)
+		jobWorker.SetPauseStore(pauseStore)
+		jobWorker.SetHistory(scheduler.History())
+		go jobWorker.Start(ctx)
+	}
+
+	// --- Прогрев кэша популярными ключами ---
+	popularDataService.SetWarmer(warmer)
+	go warmer.Start(ctx)
+	// The scheduler waits for the warm-up, which would otherwise fetch every
+	// interval item a second time right away.
+	warmedUp := make(chan struct{})
+	if warmupCfg.OnStartup {
+		readiness.NotReady("cache-warmup", "warming popular keys")
+		go func() {
+			defer close(warmedUp)
+			warmer.WarmAll(ctx)
+			readiness.Ready("cache-warmup")
+		}()
+	} else {
+		close(warmedUp)
+	}
+	afterWarmup := func(ctx context.Context) bool {
+		select {
+		case <-warmedUp:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	if schedulerCfg.Listen {
+		go func() {
+			err := postgres.Listen(ctx, pgCfg, postgresRepo.ChangesChannel, func(payload string) {
+				change, err := postgresRepo.ParseChange(payload)
+				if err != nil {
+					slog.Warn("ignoring popular data notification", "error", err)
+					return
+				}
+				scheduler.HandleChange(ctx, change)
+			}, scheduler.Reload)
+			if err != nil {
+				slog.Error("popular data listener stopped, relying on periodic reloads", "error", err)
+			}
+		}()
+	}
 
-	// --- Запуск Kafka Consumer в отдельной горутине ---
+	switch schedulerCfg.Coordination {
+	case "leader":
+		elector := coordination.NewElector(rdb, schedulerCfg.LeaderKey, schedulerCfg.InstanceID, schedulerCfg.LeaderLease)
+		adminOpts = append(adminOpts, admin.WithElector(elector))
+		go elector.Run(ctx, func(ctx context.Context, token int64) {
+			if afterWarmup(ctx) {
+				scheduler.Lead(ctx, token)
+			}
+		})
+	case "sharded":
+		membership := coordination.NewMembership(rdb, schedulerCfg.MembershipKey, schedulerCfg.InstanceID,
+			schedulerCfg.HeartbeatInterval, schedulerCfg.MemberTTL, schedulerCfg.RingReplicas)
+		membership.OnChange(scheduler.Reload)
+		scheduler.SetFilter(func(item dto.PopularDataDto) bool {
+			return membership.Owns(item.DataType + ":" + item.Key)
+		})
+		adminOpts = append(adminOpts, admin.WithMembership(membership))
+		go membership.Run(ctx)
+		go func() {
+			if afterWarmup(ctx) {
+				scheduler.Start(ctx)
+			}
+		}()
+	default:
+		go func() {
+			if afterWarmup(ctx) {
+				scheduler.Start(ctx)
+			}
+		}()
+	}
+
+	// --- Auto-promotion of popular keys ---
+	promoter := background.NewPromoter(popularityRepo, popularDataService, popularityCfg, schedulerCfg.InstanceID, scheduler.Reload)
+	go promoter.Start(ctx)
+
+	adminOpts = append(adminOpts, admin.WithScheduler(scheduler))
+	if schedulerCfg.AdminToken == "" {
+		slog.Warn("ADMIN_TOKEN is not set, pausing and triggering the scheduler is disabled")
+	}
+	adminOpts = append(adminOpts, admin.WithToken(schedulerCfg.AdminToken))
+	adminHandler := admin.NewAdminHandler(throughput, adminOpts...)
+	mux.HandleFunc("/admin/consumer", adminHandler.HandleConsumer)
+	mux.HandleFunc("/admin/scheduler", adminHandler.HandleScheduler)
+	mux.HandleFunc("/admin/scheduler/pause", adminHandler.HandlePause)
+	mux.HandleFunc("/admin/scheduler/resume", adminHandler.HandleResume)
+	mux.HandleFunc("/admin/scheduler/trigger", adminHandler.HandleTrigger)
+	mux.HandleFunc("/admin/scheduler/runs", adminHandler.HandleRuns)
+
+	// --- Запуск Consumer в отдельной горутине ---
+	consumerDone := make(chan struct{})
 	go func() {
-		if err := consumer.Run(ctx, []string{kafkaCfg.Topic}); err != nil {
-			slog.Error("kafka consumer stopped", "error", err)
+		defer close(consumerDone)
+		if err := subscriber.Run(ctx, topicRoutes.Topics(), dispatcher.Dispatch); err != nil {
+			slog.Error("consumer stopped", "error", err)
+			cancel()
 		}
 	}()
 
 	// --- Запуск HTTP сервера ---
 	srv := &http.Server{
 		Addr:    ":8080",
-		Handler: mux,
+		Handler: tracing.Middleware(mux),
 	}
 
 	go func() {
@@ -141,4 +319,89 @@ func main() {
 	if err := srv.Shutdown(ctxShutdown); err != nil {
 		slog.Error("server shutdown failed", "error", err)
 	}
+
+	// The deferred subscriber.Close must not run before Run made its final
+	// commit of the processed offsets.
+	<-consumerDone
+}
+
+func newEventRouter(repo *aggregation_data.RedisRepository, redisCfg *config.RedisConfig, cfg *config.MessagingConfig, extra ...messaging.Middleware) *messaging.EventRouter {
+	middlewares := []messaging.Middleware{
+		messaging.RecoveryMiddleware,
+		messaging.LoggingMiddleware,
+		messaging.MetricsMiddleware,
+	}
+	middlewares = append(middlewares, extra...)
+	middlewares = append(middlewares,
+		messaging.TimeoutMiddleware(cfg.HandlerTimeout),
+		messaging.ValidationMiddleware,
+	)
+
+	return messaging.NewEventRouter(
+		messaging.WithHandlers(messaging.NewWeatherEventHandler(repo, redisCfg.WeatherTTL)),
+		messaging.WithMiddleware(middlewares...),
+	)
+}
+
+func newTransport(cfg *config.MessagingConfig, kafkaCfg *config.KafkaConfig, rdb *redis.Client) (messaging.Publisher, messaging.Subscriber, error) {
+	switch cfg.Transport {
+	case "memory":
+		broker := memory.NewBroker(cfg.MemoryPartitions)
+		return broker, broker.NewSubscriber(kafkaCfg.GroupID), nil
+	case "redis":
+		publisher := redisstream.NewPublisher(rdb, int64(cfg.StreamMaxLen))
+		subscriber := redisstream.NewSubscriber(rdb, redisstream.SubscriberConfig{
+			Group:         kafkaCfg.GroupID,
+			Consumer:      cfg.StreamConsumer,
+			BatchSize:     int64(cfg.StreamBatchSize),
+			BlockTimeout:  cfg.StreamBlockTimeout,
+			ClaimMinIdle:  cfg.StreamClaimMinIdle,
+			ClaimInterval: cfg.StreamClaimInterval,
+
+			MaxDeliveries:    int64(cfg.StreamMaxDeliveries),
+			DeadLetterSuffix: cfg.StreamDeadLetterSuffix,
+		})
+		return publisher, subscriber, nil
+	case "kafka":
+		producer, err := kafka.NewKafkaProducer(kafkaCfg)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		consumer, err := kafka.NewKafkaConsumer(kafkaCfg)
+		if err != nil {
+			producer.Close()
+			return nil, nil, err
+		}
+		return producer, consumer, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown messaging transport: %s", cfg.Transport)
+	}
+}
+
+func newSerializer(cfg *config.MessagingConfig, eventMode string) (messaging.EventSerializer, error) {
+	jsonSerializer := messaging.NewJSONSerializer(eventMode)
+	if cfg.SerializationFormat == "json" {
+		return jsonSerializer, nil
+	}
+
+	// Schema IDs from a process-local registry mean nothing to other
+	// replicas, so registry formats need a shared one.
+	if cfg.SchemaRegistryURL == "" {
+		return nil, fmt.Errorf("SCHEMA_REGISTRY_URL is required for %s serialization", cfg.SerializationFormat)
+	}
+	registry := schemaregistry.NewHTTPClient(cfg.SchemaRegistryURL,
+		cfg.SchemaRegistryUsername, cfg.SchemaRegistryPassword, cfg.SchemaRegistryTimeout)
+
+	return serde.NewRegistrySerializer(cfg.SerializationFormat, registry, jsonSerializer)
+}
+
+func ensureTopics(ctx context.Context, cfg *config.KafkaConfig) error {
+	provisioner, err := kafka.NewTopicProvisioner(cfg)
+	if err != nil {
+		return err
+	}
+	defer provisioner.Close()
+
+	return provisioner.Ensure(ctx, kafka.TopicSpecs(cfg), cfg.AutoCreateTopics)
 }
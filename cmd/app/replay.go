@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+
+	"service-info-aggregator/internal/config"
+	"service-info-aggregator/internal/messaging"
+	"service-info-aggregator/internal/messaging/kafka"
+	"service-info-aggregator/internal/repository/aggregation_data"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type replayStats struct {
+	applied map[string]int
+	failed  map[string]int
+}
+
+// runReplay rebuilds the cache from the events topics:
+//
+//	app replay [-topic T] [-from-offset N | -from-timestamp RFC3339] [-dry-run]
+//
+// Without -topic every topic events are routed to is replayed, one after the
+// other. Without a starting point the topics are read from the earliest
+// retained offset.
+func runReplay(args []string) int {
+	kafkaCfg := config.NewKafkaConfig()
+	redisCfg := config.NewRedisConfig()
+	messagingCfg := config.NewMessagingConfig()
+
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	topic := fs.String("topic", "", "topic to replay (default: every routed topic)")
+	fromOffset := fs.Int64("from-offset", -1, "offset to start from in every partition")
+	fromTimestamp := fs.String("from-timestamp", "", "RFC3339 time to start from")
+	dryRun := fs.Bool("dry-run", false, "print events instead of writing them to the cache")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	topics := replayTopics(kafkaCfg, *topic)
+	opts, err := replayOptions(*fromOffset, *fromTimestamp)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	serializer, err := newSerializer(messagingCfg, kafkaCfg.EventMode)
+	if err != nil {
+		slog.Error("failed to create event serializer", "error", err)
+		return 1
+	}
+
+	var router *messaging.EventRouter
+	if !*dryRun {
+		rdb := redis.NewClient(&redis.Options{
+			Addr:     redisCfg.Addr,
+			Username: redisCfg.Username,
+			Password: redisCfg.Password,
+			DB:       redisCfg.DB,
+		})
+		defer rdb.Close()
+		if err := rdb.Ping(ctx).Err(); err != nil {
+			slog.Error("failed to connect to redis", "error", err)
+			return 1
+		}
+
+		repo := aggregation_data.NewRedisRepository(rdb, "")
+		router = newEventRouter(repo, redisCfg, messagingCfg)
+	}
+
+	replayer, err := kafka.NewReplayer(kafkaCfg, kafkaCfg.ClientID+"-replay")
+	if err != nil {
+		slog.Error("failed to create kafka replayer", "error", err)
+		return 1
+	}
+	defer replayer.Close()
+
+	stats := replayStats{applied: make(map[string]int), failed: make(map[string]int)}
+
+	handle := func(ctx context.Context, msg *messaging.Message) error {
+		event, err := serializer.Deserialize(ctx, msg)
+		if err != nil {
+			slog.Warn("skipping undecodable message", "partition", msg.Partition, "offset", msg.Offset, "error", err)
+			stats.failed["<undecodable>"]++
+			return nil
+		}
+
+		if *dryRun {
+			fmt.Printf("[%d@%d] %s %s %s %s\n", msg.Partition, msg.Offset, event.Type, event.Subject,
+				event.Time.Format(time.RFC3339), event.Data)
+			stats.applied[event.Type]++
+			return nil
+		}
+
+		if err := router.Route(ctx, event); err != nil {
+			slog.Warn("failed to apply event", "id", event.ID, "type", event.Type, "error", err)
+			stats.failed[event.Type]++
+			return nil
+		}
+		stats.applied[event.Type]++
+		return nil
+	}
+
+	read := 0
+	for _, t := range topics {
+		opts.Topic = t
+		var n int
+		n, err = replayer.Run(ctx, opts, handle)
+		read += n
+		fmt.Printf("messages read from %s: %d\n", t, n)
+		if err != nil {
+			break
+		}
+	}
+
+	fmt.Printf("messages read: %d\n", read)
+	printCounts("applied", stats.applied, *dryRun)
+	printCounts("failed", stats.failed, false)
+
+	if err != nil {
+		slog.Error("replay stopped", "error", err)
+		return 1
+	}
+	return 0
+}
+
+// replayTopics returns topic, or every topic events are routed to.
+func replayTopics(cfg *config.KafkaConfig, topic string) []string {
+	if topic != "" {
+		return []string{topic}
+	}
+	return messaging.NewTopicRoutes(cfg.Topic, cfg.TopicRoutes).Topics()
+}
+
+func replayOptions(fromOffset int64, fromTimestamp string) (kafka.ReplayOptions, error) {
+	opts := kafka.ReplayOptions{FromOffset: fromOffset}
+	if fromTimestamp == "" {
+		return opts, nil
+	}
+	if fromOffset >= 0 {
+		return opts, errors.New("-from-offset and -from-timestamp are mutually exclusive")
+	}
+	t, err := time.Parse(time.RFC3339, fromTimestamp)
+	if err != nil {
+		return opts, fmt.Errorf("invalid -from-timestamp: %w", err)
+	}
+	opts.FromTimestamp = t
+	return opts, nil
+}
+
+func printCounts(label string, counts map[string]int, dryRun bool) {
+	if dryRun {
+		label = "would apply"
+	}
+
+	types := make([]string, 0, len(counts))
+	for t := range counts {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	for _, t := range types {
+		fmt.Printf("%s %s: %d\n", label, t, counts[t])
+	}
+}
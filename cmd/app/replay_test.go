@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"service-info-aggregator/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayTopics(t *testing.T) {
+	cfg := &config.KafkaConfig{
+		Topic:       "events.default",
+		TopicRoutes: map[string]string{"weather": "events.weather", "rates": "events.default"},
+	}
+
+	assert.Equal(t, []string{"events.default", "events.weather"}, replayTopics(cfg, ""))
+	assert.Equal(t, []string{"events.news"}, replayTopics(cfg, "events.news"))
+}
+
+func TestReplayOptions(t *testing.T) {
+	opts, err := replayOptions(-1, "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), opts.FromOffset)
+	assert.True(t, opts.FromTimestamp.IsZero())
+
+	opts, err = replayOptions(42, "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), opts.FromOffset)
+
+	opts, err = replayOptions(-1, "2026-05-01T10:00:00Z")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC), opts.FromTimestamp)
+
+	_, err = replayOptions(42, "2026-05-01T10:00:00Z")
+	assert.ErrorContains(t, err, "mutually exclusive")
+
+	_, err = replayOptions(-1, "yesterday")
+	assert.ErrorContains(t, err, "invalid -from-timestamp")
+}
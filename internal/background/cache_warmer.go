@@ -0,0 +1,228 @@
+package background
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"service-info-aggregator/internal/config"
+	"service-info-aggregator/internal/metrics"
+	"service-info-aggregator/internal/model/dto"
+	"service-info-aggregator/internal/repository/refresh_jobs"
+	"service-info-aggregator/internal/service/aggregation"
+	"service-info-aggregator/internal/service/popular_data"
+)
+
+var ErrWarmupQueueFull = errors.New("cache warm-up queue is full")
+
+const cachePollInterval = 100 * time.Millisecond
+
+// Cache tells which keys hold a value that has not expired yet.
+type Cache interface {
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+// CacheWarmer fetches popular keys ahead of their scheduled refresh: all of
+// them at startup and single items as they are saved. Fetches are limited to
+// Rate per second so a warm-up does not flood the providers.
+type CacheWarmer struct {
+	popularDataService *popular_data.PopularDataService
+	aggregationService *aggregation.AggregationService
+	providers          map[string]aggregation.Provider
+	cfg                *config.WarmupConfig
+	queue              refresh_jobs.Repository
+	cache              Cache
+	requests           chan dto.PopularDataDto
+}
+
+func NewCacheWarmer(ps *popular_data.PopularDataService, as *aggregation.AggregationService,
+	cfg *config.WarmupConfig, providers ...aggregation.Provider) *CacheWarmer {
+	byName := make(map[string]aggregation.Provider, len(providers))
+	for _, p := range providers {
+		byName[p.Name()] = p
+	}
+
+	return &CacheWarmer{
+		popularDataService: ps,
+		aggregationService: as,
+		providers:          byName,
+		cfg:                cfg,
+		requests:           make(chan dto.PopularDataDto, max(cfg.QueueSize, 1)),
+	}
+}
+
+// SetQueue makes Warm enqueue refresh jobs, so warm-ups of saved items
+// survive restarts and run on any replica.
+func (w *CacheWarmer) SetQueue(queue refresh_jobs.Repository) {
+	w.queue = queue
+}
+
+// SetCache makes WarmAll skip keys that are cached already, e.g. by another
+// replica or before a restart.
+func (w *CacheWarmer) SetCache(cache Cache) {
+	w.cache = cache
+}
+
+// Warm enqueues an immediate fetch of item. It implements
+// popular_data.Warmer.
+func (w *CacheWarmer) Warm(ctx context.Context, item dto.PopularDataDto) error {
+	if w.queue != nil {
+		added, err := w.queue.Enqueue(ctx, &dto.RefreshJobDto{
+			DataType: item.DataType,
+			Key:      item.Key,
+			Priority: item.Priority,
+		})
+		if added {
+			metrics.RefreshJobsEnqueued.WithLabelValues(item.DataType).Inc()
+		}
+		return err
+	}
+
+	select {
+	case w.requests <- item:
+		return nil
+	default:
+		return ErrWarmupQueueFull
+	}
+}
+
+// Start fetches the items passed to Warm until ctx is cancelled.
+func (w *CacheWarmer) Start(ctx context.Context) {
+	limit := w.limiter()
+	defer limit.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case item := <-w.requests:
+			select {
+			case <-ctx.Done():
+				return
+			case <-limit.C:
+			}
+			w.fetch(ctx, item)
+		}
+	}
+}
+
+// WarmAll fetches every popular item that is not cached once and returns
+// when all of them were tried and the fetched ones reached the cache,
+// MaxDuration passed or ctx was cancelled. Fetches only publish results, so
+// with a cache set WarmAll waits for the consumer to write them. Failures are
+// logged and left to the scheduler.
+func (w *CacheWarmer) WarmAll(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, w.cfg.MaxDuration)
+	defer cancel()
+
+	items, err := w.popularDataService.GetAll(ctx)
+	if err != nil {
+		slog.Error("cache warm-up could not load popular data", "error", err)
+		return
+	}
+
+	slog.Info("cache warm-up started", "items", len(items), "rate", w.cfg.Rate)
+	started := time.Now()
+
+	limit := w.limiter()
+	defer limit.Stop()
+
+	var warmed, failed, cached int
+	var pending []dto.PopularDataDto
+	for _, item := range items {
+		if w.cached(ctx, item) {
+			cached++
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.Warn("cache warm-up stopped early",
+				"warmed", warmed,
+				"failed", failed,
+				"cached", cached,
+				"skipped", len(items)-warmed-failed-cached,
+				"error", ctx.Err())
+			return
+		case <-limit.C:
+		}
+
+		if w.fetch(ctx, item) {
+			warmed++
+			pending = append(pending, item)
+		} else {
+			failed++
+		}
+	}
+
+	if err := w.awaitCached(ctx, pending); err != nil {
+		slog.Warn("cache warm-up results did not reach the cache in time", "warmed", warmed, "error", err)
+		return
+	}
+	slog.Info("cache warm-up finished", "warmed", warmed, "failed", failed, "cached", cached, "duration", time.Since(started))
+}
+
+func (w *CacheWarmer) awaitCached(ctx context.Context, items []dto.PopularDataDto) error {
+	if w.cache == nil {
+		return nil
+	}
+
+	ticker := time.NewTicker(cachePollInterval)
+	defer ticker.Stop()
+
+	for {
+		var missing []dto.PopularDataDto
+		for _, item := range items {
+			if !w.cached(ctx, item) {
+				missing = append(missing, item)
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+		items = missing
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d keys not cached: %w", len(missing), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *CacheWarmer) cached(ctx context.Context, item dto.PopularDataDto) bool {
+	provider, ok := w.providers[item.DataType]
+	if w.cache == nil || !ok {
+		return false
+	}
+
+	exists, err := w.cache.Exists(ctx, provider.CacheKey(item.Key))
+	if err != nil {
+		slog.Warn("cache warm-up could not check cached key", "type", item.DataType, "key", item.Key, "error", err)
+		return false
+	}
+	return exists
+}
+
+func (w *CacheWarmer) fetch(ctx context.Context, item dto.PopularDataDto) bool {
+	provider, ok := w.providers[item.DataType]
+	if !ok {
+		slog.Warn("unknown data type", "type", item.DataType)
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, w.cfg.FetchTimeout)
+	defer cancel()
+
+	if _, err := w.aggregationService.Execute(ctx, provider, item.Key); err != nil {
+		slog.Error("cache warm-up failed", "type", item.DataType, "key", item.Key, "error", err)
+		return false
+	}
+	return true
+}
+
+func (w *CacheWarmer) limiter() *time.Ticker {
+	return time.NewTicker(time.Second / time.Duration(max(w.cfg.Rate, 1)))
+}
@@ -0,0 +1,155 @@
+package background_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"service-info-aggregator/internal/background"
+	"service-info-aggregator/internal/config"
+	"service-info-aggregator/internal/messaging"
+	"service-info-aggregator/internal/messaging/memory"
+	"service-info-aggregator/internal/model/dto"
+	"service-info-aggregator/internal/service/aggregation"
+	"service-info-aggregator/internal/service/popular_data"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestWarmer(items []dto.PopularDataDto, cfg *config.WarmupConfig, provider aggregation.Provider) *background.CacheWarmer {
+	aggService := aggregation.NewAggregationService(memory.NewBroker(1), messaging.NewJSONSerializer(messaging.EventModeStructured),
+		messaging.NewTopicRoutes("events", nil), "", "/test")
+	service := popular_data.NewPopularDataService(newMemoryRepository(items...))
+
+	return background.NewCacheWarmer(service, aggService, cfg, provider)
+}
+
+func TestCacheWarmer_WarmAllIsRateLimited(t *testing.T) {
+	provider := &recordingProvider{}
+	warmer := newTestWarmer([]dto.PopularDataDto{
+		{ID: 1, DataType: "weather", Key: "Moscow"},
+		{ID: 2, DataType: "weather", Key: "Berlin"},
+		{ID: 3, DataType: "weather", Key: "Paris"},
+	}, &config.WarmupConfig{
+		Rate:         20,
+		FetchTimeout: time.Second,
+		MaxDuration:  time.Second,
+	}, provider)
+
+	started := time.Now()
+	warmer.WarmAll(context.Background())
+
+	assert.Equal(t, []string{"Moscow", "Berlin", "Paris"}, provider.calls())
+	assert.GreaterOrEqual(t, time.Since(started), 150*time.Millisecond)
+}
+
+type cachedKeys map[string]bool
+
+func (c cachedKeys) Exists(ctx context.Context, key string) (bool, error) {
+	return c[key], nil
+}
+
+// consumerCache caches keys once the consumer applied them, a while after
+// they were fetched.
+type consumerCache struct {
+	mu     sync.Mutex
+	cached map[string]bool
+}
+
+func (c *consumerCache) Exists(ctx context.Context, key string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cached[key], nil
+}
+
+func (c *consumerCache) apply(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cached[key] = true
+}
+
+func TestCacheWarmer_WarmAllWaitsForResultsInCache(t *testing.T) {
+	provider := &recordingProvider{}
+	warmer := newTestWarmer([]dto.PopularDataDto{{ID: 1, DataType: "weather", Key: "Moscow"}}, &config.WarmupConfig{
+		Rate:         100,
+		FetchTimeout: time.Second,
+		MaxDuration:  time.Second,
+	}, provider)
+	cache := &consumerCache{cached: make(map[string]bool)}
+	warmer.SetCache(cache)
+
+	time.AfterFunc(300*time.Millisecond, func() { cache.apply("weather:Moscow") })
+
+	started := time.Now()
+	warmer.WarmAll(context.Background())
+
+	assert.Equal(t, []string{"Moscow"}, provider.calls())
+	assert.GreaterOrEqual(t, time.Since(started), 300*time.Millisecond)
+	assert.Less(t, time.Since(started), time.Second)
+}
+
+func TestCacheWarmer_WarmAllGivesUpAfterMaxDuration(t *testing.T) {
+	warmer := newTestWarmer([]dto.PopularDataDto{{ID: 1, DataType: "weather", Key: "Moscow"}}, &config.WarmupConfig{
+		Rate:         100,
+		FetchTimeout: time.Second,
+		MaxDuration:  200 * time.Millisecond,
+	}, &recordingProvider{})
+	warmer.SetCache(&consumerCache{cached: make(map[string]bool)})
+
+	started := time.Now()
+	warmer.WarmAll(context.Background())
+
+	assert.GreaterOrEqual(t, time.Since(started), 200*time.Millisecond)
+}
+
+func TestCacheWarmer_WarmAllSkipsCachedKeys(t *testing.T) {
+	provider := &recordingProvider{}
+	warmer := newTestWarmer([]dto.PopularDataDto{
+		{ID: 1, DataType: "weather", Key: "Moscow"},
+		{ID: 2, DataType: "weather", Key: "Berlin"},
+		{ID: 3, DataType: "weather", Key: "Paris"},
+	}, &config.WarmupConfig{
+		Rate:         100,
+		FetchTimeout: time.Second,
+		MaxDuration:  200 * time.Millisecond,
+	}, provider)
+	warmer.SetCache(cachedKeys{"weather:Moscow": true, "weather:Paris": true})
+
+	warmer.WarmAll(context.Background())
+
+	assert.Equal(t, []string{"Berlin"}, provider.calls())
+}
+
+func TestCacheWarmer_WarmFetchesInBackground(t *testing.T) {
+	provider := &recordingProvider{}
+	warmer := newTestWarmer(nil, &config.WarmupConfig{
+		Rate:         100,
+		FetchTimeout: time.Second,
+		QueueSize:    1,
+	}, provider)
+
+	require.NoError(t, warmer.Warm(context.Background(), dto.PopularDataDto{DataType: "weather", Key: "Oslo"}))
+	assert.ErrorIs(t, warmer.Warm(context.Background(), dto.PopularDataDto{DataType: "weather", Key: "Rome"}),
+		background.ErrWarmupQueueFull)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go warmer.Start(ctx)
+
+	require.Eventually(t, func() bool { return len(provider.calls()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"Oslo"}, provider.calls())
+}
+
+func TestCacheWarmer_WarmEnqueuesJobWithQueue(t *testing.T) {
+	queue := newMemoryJobQueue()
+	warmer := newTestWarmer(nil, &config.WarmupConfig{Rate: 1}, &recordingProvider{})
+	warmer.SetQueue(queue)
+
+	require.NoError(t, warmer.Warm(context.Background(), dto.PopularDataDto{DataType: "weather", Key: "Oslo"}))
+
+	jobs := queue.snapshot()
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "Oslo", jobs[0].Key)
+}
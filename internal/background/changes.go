@@ -0,0 +1,48 @@
+package background
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"service-info-aggregator/internal/model/dto"
+	popularDataRepo "service-info-aggregator/internal/repository/popular_data"
+)
+
+// HandleChange applies one popular_data change to the running schedule
+// instead of waiting for the next reload. Replicas not running the scheduler
+// drop changes; they load every item when they start.
+func (s *PriorityScheduler) HandleChange(ctx context.Context, change popularDataRepo.Change) {
+	if !s.running.Load() {
+		return
+	}
+
+	var item *dto.PopularDataDto
+	if change.Op != "DELETE" {
+		var err error
+		item, err = s.popularDataService.GetById(ctx, change.ID)
+		if err != nil {
+			slog.Error("failed to load changed popular data item, reloading", "id", change.ID, "error", err)
+			s.Reload()
+			return
+		}
+	}
+	if item != nil && s.filter != nil && !s.filter(*item) {
+		item = nil
+	}
+
+	err := s.command(ctx, func() error {
+		if item != nil {
+			s.schedule.upsert(*item, time.Now(), timingOf)
+			return nil
+		}
+		if it, ok := s.schedule.items[change.ID]; ok {
+			s.schedule.remove(it)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, ErrNotRunning) {
+		slog.Warn("failed to apply popular data change", "op", change.Op, "id", change.ID, "error", err)
+	}
+}
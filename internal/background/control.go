@@ -0,0 +1,190 @@
+package background
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+var (
+	ErrNotRunning   = errors.New("scheduler is not running on this instance")
+	ErrItemNotFound = errors.New("item is not scheduled on this instance")
+)
+
+const (
+	triggerScheduled = "scheduled"
+	triggerManual    = "manual"
+)
+
+// triggerPoll bounds how long a trigger made on another replica waits.
+const triggerPoll = time.Second
+
+// TriggerStore passes manual triggers between replicas. An id of 0 triggers
+// every item.
+type TriggerStore interface {
+	Trigger(ctx context.Context, id int) error
+	// Since returns the triggers after cursor and the next cursor; an empty
+	// cursor returns only the cursor of the latest trigger.
+	Since(ctx context.Context, cursor string) ([]int, string, error)
+}
+
+const (
+	outcomeSuccess = "success"
+	outcomeError   = "error"
+	outcomeRetry   = "retry"
+	outcomeFailed  = "failed"
+)
+
+// RunRecord describes one refresh. Runs of queued jobs carry the job ID and
+// attempt instead of the item ID; their outcome is success, retry or failed.
+type RunRecord struct {
+	ItemID     int       `json:"item_id,omitempty"`
+	JobID      int64     `json:"job_id,omitempty"`
+	Attempt    int       `json:"attempt,omitempty"`
+	DataType   string    `json:"type"`
+	Key        string    `json:"key"`
+	Trigger    string    `json:"trigger"`
+	Started    time.Time `json:"started"`
+	DurationMs int64     `json:"duration_ms"`
+	Outcome    string    `json:"outcome"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Pause stops scheduled refreshes of one data type, or of everything when
+// dataType is empty, on every replica. Paused items skip their runs; manual
+// triggers still run.
+func (s *PriorityScheduler) Pause(ctx context.Context, dataType string) error {
+	return s.pauses.pause(ctx, dataType)
+}
+
+// Resume lifts a pause of one data type, or every pause when dataType is
+// empty.
+func (s *PriorityScheduler) Resume(ctx context.Context, dataType string) error {
+	return s.pauses.resume(ctx, dataType)
+}
+
+func (s *PriorityScheduler) Paused(ctx context.Context) (PauseState, error) {
+	return s.pauses.get(ctx)
+}
+
+// SetPauseStore shares pauses through store instead of keeping them in this
+// process. Call it before Start.
+func (s *PriorityScheduler) SetPauseStore(store PauseStore) {
+	s.pauses.setStore(store)
+}
+
+// SetTriggerStore sends triggers through store to whichever replica runs the
+// item, so any replica can accept them. Call it before Start.
+func (s *PriorityScheduler) SetTriggerStore(store TriggerStore) {
+	s.triggers = store
+}
+
+// Trigger makes one item due now, even when its type is paused. An item that
+// is refreshing already is left alone. With a trigger store the item runs
+// within triggerPoll on the replica that schedules it.
+func (s *PriorityScheduler) Trigger(ctx context.Context, id int) error {
+	if s.triggers != nil {
+		item, err := s.popularDataService.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if item == nil {
+			return ErrItemNotFound
+		}
+		return s.triggers.Trigger(ctx, id)
+	}
+
+	return s.command(ctx, func() error {
+		if !s.schedule.trigger(id, time.Now()) {
+			return ErrItemNotFound
+		}
+		return nil
+	})
+}
+
+// TriggerAll makes every scheduled item due now and returns how many there
+// are.
+func (s *PriorityScheduler) TriggerAll(ctx context.Context) (int, error) {
+	if s.triggers != nil {
+		items, err := s.popularDataService.GetAll(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return len(items), s.triggers.Trigger(ctx, 0)
+	}
+
+	var n int
+	err := s.command(ctx, func() error {
+		now := time.Now()
+		for id := range s.schedule.items {
+			s.schedule.trigger(id, now)
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// applyTriggers runs the triggers stored since the last call. Items this
+// replica does not schedule are left to the replica that does.
+func (s *PriorityScheduler) applyTriggers(ctx context.Context) {
+	ids, cursor, err := s.triggers.Since(ctx, s.triggerCursor)
+	if err != nil {
+		slog.Warn("failed to read scheduler triggers", "error", err)
+		return
+	}
+	s.triggerCursor = cursor
+
+	now := time.Now()
+	for _, id := range ids {
+		if id != 0 {
+			s.schedule.trigger(id, now)
+			continue
+		}
+		for id := range s.schedule.items {
+			s.schedule.trigger(id, now)
+		}
+	}
+}
+
+// command runs fn on the scheduler goroutine, which owns the schedule. It
+// gives up with ErrNotRunning when Start returns before taking fn.
+func (s *PriorityScheduler) command(ctx context.Context, fn func() error) error {
+	s.stopMu.Lock()
+	stopped := s.stopped
+	s.stopMu.Unlock()
+	if stopped == nil || !s.running.Load() {
+		return ErrNotRunning
+	}
+
+	result := make(chan error, 1)
+	select {
+	case s.commands <- func() { result <- fn() }:
+	case <-stopped:
+		return ErrNotRunning
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	// The loop runs fn as soon as it takes it.
+	return <-result
+}
+
+func (s *PriorityScheduler) Running() bool {
+	return s.running.Load()
+}
+
+// History returns the run history, which job workers share to record the
+// runs of queued refreshes.
+func (s *PriorityScheduler) History() *RunHistory {
+	return s.history
+}
+
+func (s *PriorityScheduler) HistorySize() int {
+	return s.history.Size()
+}
+
+// Runs returns up to limit of the most recent runs, newest first.
+func (s *PriorityScheduler) Runs(limit int) []RunRecord {
+	return s.history.Runs(limit)
+}
@@ -0,0 +1,45 @@
+package background
+
+import "sync"
+
+// RunHistory keeps the most recent runs in a ring buffer.
+type RunHistory struct {
+	mu   sync.Mutex
+	size int
+	runs []RunRecord
+	next int
+}
+
+func NewRunHistory(size int) *RunHistory {
+	return &RunHistory{size: max(size, 1)}
+}
+
+func (h *RunHistory) Size() int {
+	return h.size
+}
+
+func (h *RunHistory) Record(run RunRecord) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.runs) < h.size {
+		h.runs = append(h.runs, run)
+		h.next = len(h.runs) % h.size
+		return
+	}
+	h.runs[h.next] = run
+	h.next = (h.next + 1) % h.size
+}
+
+// Runs returns up to limit of the most recent runs, newest first.
+func (h *RunHistory) Runs(limit int) []RunRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	n := min(limit, len(h.runs))
+	runs := make([]RunRecord, 0, n)
+	for i := range n {
+		idx := (h.next - 1 - i + len(h.runs)) % len(h.runs)
+		runs = append(runs, h.runs[idx])
+	}
+	return runs
+}
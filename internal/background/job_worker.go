@@ -0,0 +1,248 @@
+package background
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"service-info-aggregator/internal/config"
+	"service-info-aggregator/internal/metrics"
+	"service-info-aggregator/internal/model/dto"
+	"service-info-aggregator/internal/repository/refresh_jobs"
+	"service-info-aggregator/internal/service/aggregation"
+)
+
+// JobWorker runs refresh jobs from the persistent queue. Every replica runs
+// one; the queue hands each job to a single worker at a time, and a job
+// whose worker died is claimed again once its visibility timeout passes.
+// Like the scheduler, it respects pauses and per-provider limits, and its
+// results carry the fencing token of the leader that enqueued the job.
+type JobWorker struct {
+	repo               refresh_jobs.Repository
+	aggregationService *aggregation.AggregationService
+	providers          map[string]aggregation.Provider
+	cfg                *config.JobQueueConfig
+	owner              string
+	pauses             *pauses
+	history            *RunHistory
+	slots              chan struct{}
+	wg                 sync.WaitGroup
+
+	mu         sync.Mutex
+	byProvider map[string]int
+}
+
+func NewJobWorker(repo refresh_jobs.Repository, as *aggregation.AggregationService, cfg *config.JobQueueConfig,
+	owner string, providers ...aggregation.Provider) *JobWorker {
+	byName := make(map[string]aggregation.Provider, len(providers))
+	for _, p := range providers {
+		byName[p.Name()] = p
+	}
+
+	return &JobWorker{
+		repo:               repo,
+		aggregationService: as,
+		providers:          byName,
+		cfg:                cfg,
+		owner:              owner,
+		pauses:             newPauses(),
+		history:            NewRunHistory(1),
+		slots:              make(chan struct{}, max(cfg.Workers, 1)),
+		byProvider:         make(map[string]int),
+	}
+}
+
+// SetPauseStore makes the worker skip jobs of types paused in store. Call it
+// before Start.
+func (w *JobWorker) SetPauseStore(store PauseStore) {
+	w.pauses.setStore(store)
+}
+
+// SetHistory records the runs in history, e.g. the scheduler's, so the admin
+// endpoint lists them. Call it before Start.
+func (w *JobWorker) SetHistory(history *RunHistory) {
+	w.history = history
+}
+
+// Start claims jobs for the free workers every PollInterval until ctx is
+// cancelled, then waits for the running jobs.
+func (w *JobWorker) Start(ctx context.Context) {
+	slog.Info("refresh job worker started", "owner", w.owner, "workers", cap(w.slots))
+
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		w.poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			w.wg.Wait()
+			slog.Info("refresh job worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *JobWorker) poll(ctx context.Context) {
+	free := cap(w.slots) - len(w.slots)
+	if free == 0 {
+		return
+	}
+
+	filter, err := w.claimFilter(ctx)
+	if err != nil {
+		slog.Warn("failed to read scheduler pauses, using the last known state", "error", err)
+	}
+
+	jobs, err := w.repo.Claim(ctx, w.owner, free, w.cfg.VisibilityTimeout, filter)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Error("failed to claim refresh jobs", "error", err)
+		}
+		return
+	}
+
+	for _, job := range jobs {
+		if !w.acquire(job.DataType) {
+			// The claim returned more jobs of this provider than it may run.
+			if err := w.repo.Release(ctx, job.ID, w.owner); err != nil {
+				slog.Error("failed to release refresh job", "id", job.ID, "error", err)
+			}
+			continue
+		}
+
+		w.slots <- struct{}{}
+		w.wg.Add(1)
+		go func() {
+			defer func() {
+				w.releaseProvider(job.DataType)
+				<-w.slots
+				w.wg.Done()
+			}()
+			w.run(ctx, job)
+		}()
+	}
+}
+
+func (w *JobWorker) claimFilter(ctx context.Context) (refresh_jobs.ClaimFilter, error) {
+	paused, err := w.pauses.get(ctx)
+	filter := refresh_jobs.ClaimFilter{PausedTypes: paused.Types, AllPaused: paused.All}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for dataType, limit := range w.cfg.ProviderConcurrency {
+		if limit > 0 && w.byProvider[dataType] >= limit {
+			filter.FullTypes = append(filter.FullTypes, dataType)
+		}
+	}
+	return filter, err
+}
+
+func (w *JobWorker) acquire(dataType string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if limit := w.cfg.ProviderConcurrency[dataType]; limit > 0 && w.byProvider[dataType] >= limit {
+		return false
+	}
+	w.byProvider[dataType]++
+	return true
+}
+
+func (w *JobWorker) releaseProvider(dataType string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.byProvider[dataType]--
+}
+
+func (w *JobWorker) run(ctx context.Context, job dto.RefreshJobDto) {
+	started := time.Now()
+	err := w.execute(ctx, job)
+	duration := time.Since(started)
+	metrics.SchedulerRefreshDuration.WithLabelValues(job.DataType).Observe(duration.Seconds())
+
+	run := RunRecord{
+		JobID:      job.ID,
+		Attempt:    job.Attempts,
+		DataType:   job.DataType,
+		Key:        job.Key,
+		Trigger:    triggerScheduled,
+		Started:    started,
+		DurationMs: duration.Milliseconds(),
+		Outcome:    outcomeSuccess,
+	}
+	if job.Manual {
+		run.Trigger = triggerManual
+	}
+
+	// The outcome is recorded even when shutdown cancelled the refresh, so
+	// the job is retried without waiting for its visibility timeout.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	if err == nil {
+		w.history.Record(run)
+		metrics.RefreshJobsProcessed.WithLabelValues(job.DataType, "done").Inc()
+		if err := w.repo.Complete(ctx, job.ID, w.owner); err != nil {
+			slog.Error("failed to complete refresh job", "id", job.ID, "error", err)
+		}
+		return
+	}
+	run.Error = err.Error()
+
+	if job.Attempts >= w.cfg.MaxAttempts {
+		run.Outcome = outcomeFailed
+		w.history.Record(run)
+		metrics.RefreshJobsProcessed.WithLabelValues(job.DataType, "failed").Inc()
+		slog.Error("refresh job failed",
+			"id", job.ID,
+			"type", job.DataType,
+			"key", job.Key,
+			"attempts", job.Attempts,
+			"error", err)
+		if err := w.repo.Fail(ctx, job.ID, w.owner); err != nil {
+			slog.Error("failed to remove failed refresh job", "id", job.ID, "error", err)
+		}
+		return
+	}
+
+	run.Outcome = outcomeRetry
+	w.history.Record(run)
+	retryAt := time.Now().Add(w.backoff(job.Attempts))
+	metrics.RefreshJobsProcessed.WithLabelValues(job.DataType, "retry").Inc()
+	slog.Warn("refresh job will be retried",
+		"id", job.ID,
+		"type", job.DataType,
+		"key", job.Key,
+		"attempts", job.Attempts,
+		"retry_at", retryAt,
+		"error", err)
+	if err := w.repo.Retry(ctx, job.ID, w.owner, retryAt, err.Error()); err != nil {
+		slog.Error("failed to reschedule refresh job", "id", job.ID, "error", err)
+	}
+}
+
+func (w *JobWorker) execute(ctx context.Context, job dto.RefreshJobDto) error {
+	provider, ok := w.providers[job.DataType]
+	if !ok {
+		return fmt.Errorf("unknown data type: %s", job.DataType)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, w.cfg.FetchTimeout)
+	defer cancel()
+
+	_, err := w.aggregationService.ExecuteFenced(ctx, provider, job.Key, job.Token)
+	return err
+}
+
+// backoff doubles BackoffBase with every attempt, up to BackoffMax.
+func (w *JobWorker) backoff(attempts int) time.Duration {
+	delay := w.cfg.BackoffBase
+	for i := 1; i < attempts && delay < w.cfg.BackoffMax; i++ {
+		delay *= 2
+	}
+	return min(delay, w.cfg.BackoffMax)
+}
@@ -0,0 +1,347 @@
+package background_test
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"service-info-aggregator/internal/background"
+	"service-info-aggregator/internal/config"
+	"service-info-aggregator/internal/messaging"
+	"service-info-aggregator/internal/messaging/memory"
+	"service-info-aggregator/internal/model/dto"
+	"service-info-aggregator/internal/repository/refresh_jobs"
+	"service-info-aggregator/internal/service/aggregation"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryJobQueue struct {
+	mu     sync.Mutex
+	nextID int64
+	jobs   map[int64]*dto.RefreshJobDto
+}
+
+func newMemoryJobQueue() *memoryJobQueue {
+	return &memoryJobQueue{jobs: make(map[int64]*dto.RefreshJobDto)}
+}
+
+func (q *memoryJobQueue) Enqueue(ctx context.Context, job *dto.RefreshJobDto) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, j := range q.jobs {
+		if j.DataType == job.DataType && j.Key == job.Key {
+			j.Manual = j.Manual || job.Manual
+			j.Token = max(j.Token, job.Token)
+			return false, nil
+		}
+	}
+	q.nextID++
+	q.jobs[q.nextID] = &dto.RefreshJobDto{ID: q.nextID, DataType: job.DataType, Key: job.Key, Manual: job.Manual,
+		Token: job.Token, Status: refresh_jobs.StatusPending, RunAt: time.Now()}
+	return true, nil
+}
+
+func (q *memoryJobQueue) Claim(ctx context.Context, owner string, limit int, visibility time.Duration,
+	filter refresh_jobs.ClaimFilter) ([]dto.RefreshJobDto, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var claimed []dto.RefreshJobDto
+	for _, id := range slices.Sorted(maps.Keys(q.jobs)) {
+		j := q.jobs[id]
+		if len(claimed) == limit {
+			break
+		}
+		if slices.Contains(filter.FullTypes, j.DataType) ||
+			!j.Manual && (filter.AllPaused || slices.Contains(filter.PausedTypes, j.DataType)) {
+			continue
+		}
+		if j.Status == refresh_jobs.StatusPending && !j.RunAt.After(time.Now()) {
+			j.Status = refresh_jobs.StatusRunning
+			j.Attempts++
+			claimed = append(claimed, *j)
+		}
+	}
+	return claimed, nil
+}
+
+func (q *memoryJobQueue) Release(ctx context.Context, id int64, owner string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.jobs[id].Status = refresh_jobs.StatusPending
+	q.jobs[id].Attempts--
+	return nil
+}
+
+func (q *memoryJobQueue) Complete(ctx context.Context, id int64, owner string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.jobs, id)
+	return nil
+}
+
+func (q *memoryJobQueue) Retry(ctx context.Context, id int64, owner string, runAt time.Time, cause string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.jobs[id].Status, q.jobs[id].RunAt, q.jobs[id].LastError = refresh_jobs.StatusPending, runAt, cause
+	return nil
+}
+
+func (q *memoryJobQueue) Fail(ctx context.Context, id int64, owner string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.jobs, id)
+	return nil
+}
+
+func (q *memoryJobQueue) snapshot() []dto.RefreshJobDto {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobs := make([]dto.RefreshJobDto, 0, len(q.jobs))
+	for _, j := range q.jobs {
+		jobs = append(jobs, *j)
+	}
+	return jobs
+}
+
+type failingProvider struct{}
+
+func (p *failingProvider) Name() string { return "news" }
+
+func (p *failingProvider) CacheKey(param string) string { return "news:" + param }
+
+func (p *failingProvider) Fetch(ctx context.Context, param string) (any, error) {
+	return nil, errors.New("upstream unavailable")
+}
+
+func newTestJobWorker(queue refresh_jobs.Repository, cfg *config.JobQueueConfig, providers ...aggregation.Provider) *background.JobWorker {
+	return newTestJobWorkerWithBroker(memory.NewBroker(1), queue, cfg, providers...)
+}
+
+func newTestJobWorkerWithBroker(broker *memory.Broker, queue refresh_jobs.Repository, cfg *config.JobQueueConfig,
+	providers ...aggregation.Provider) *background.JobWorker {
+	aggService := aggregation.NewAggregationService(broker, messaging.NewJSONSerializer(messaging.EventModeStructured),
+		messaging.NewTopicRoutes("events", nil), "", "/test")
+	return background.NewJobWorker(queue, aggService, cfg, "replica-1", providers...)
+}
+
+func TestJobWorker_CompletesRetriesAndFails(t *testing.T) {
+	queue := newMemoryJobQueue()
+	provider := &recordingProvider{}
+	history := background.NewRunHistory(10)
+
+	worker := newTestJobWorker(queue, &config.JobQueueConfig{
+		Workers:           2,
+		PollInterval:      5 * time.Millisecond,
+		VisibilityTimeout: time.Minute,
+		FetchTimeout:      time.Second,
+		MaxAttempts:       3,
+		BackoffBase:       time.Millisecond,
+		BackoffMax:        2 * time.Millisecond,
+	}, provider, &failingProvider{})
+	worker.SetHistory(history)
+
+	_, err := queue.Enqueue(context.Background(), &dto.RefreshJobDto{DataType: "weather", Key: "Moscow"})
+	require.NoError(t, err)
+	_, err = queue.Enqueue(context.Background(), &dto.RefreshJobDto{DataType: "news", Key: "tech"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go worker.Start(ctx)
+
+	// Both jobs leave the queue: one done, one out of attempts.
+	require.Eventually(t, func() bool {
+		return len(queue.snapshot()) == 0 && len(history.Runs(10)) == 4
+	}, 2*time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"Moscow"}, provider.calls())
+
+	outcomes := map[string]int{}
+	for _, run := range history.Runs(10) {
+		outcomes[run.Outcome]++
+	}
+	assert.Equal(t, map[string]int{"success": 1, "retry": 2, "failed": 1}, outcomes)
+
+	failed := history.Runs(1)[0]
+	assert.Equal(t, "tech", failed.Key)
+	assert.Equal(t, 3, failed.Attempt)
+	assert.Equal(t, "upstream unavailable", failed.Error)
+}
+
+func TestJobWorker_SkipsPausedTypesButRunsManualJobs(t *testing.T) {
+	queue := newMemoryJobQueue()
+	provider := &recordingProvider{}
+	worker := newTestJobWorker(queue, &config.JobQueueConfig{
+		Workers:      2,
+		PollInterval: 5 * time.Millisecond,
+		FetchTimeout: time.Second,
+		MaxAttempts:  1,
+	}, provider)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pauses := &sharedPauses{}
+	require.NoError(t, pauses.Pause(ctx, "weather"))
+	worker.SetPauseStore(pauses)
+
+	_, err := queue.Enqueue(ctx, &dto.RefreshJobDto{DataType: "weather", Key: "Moscow"})
+	require.NoError(t, err)
+	_, err = queue.Enqueue(ctx, &dto.RefreshJobDto{DataType: "weather", Key: "Berlin", Manual: true})
+	require.NoError(t, err)
+
+	go worker.Start(ctx)
+
+	require.Eventually(t, func() bool { return len(provider.calls()) == 1 }, time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, []string{"Berlin"}, provider.calls())
+
+	require.NoError(t, pauses.Resume(ctx, ""))
+	require.Eventually(t, func() bool { return len(provider.calls()) == 2 }, 2*time.Second, 5*time.Millisecond)
+}
+
+func TestJobWorker_LimitsProviderConcurrency(t *testing.T) {
+	queue := newMemoryJobQueue()
+	provider := &slowProvider{delay: 20 * time.Millisecond}
+	worker := newTestJobWorker(queue, &config.JobQueueConfig{
+		Workers:             3,
+		PollInterval:        time.Millisecond,
+		FetchTimeout:        time.Second,
+		MaxAttempts:         1,
+		ProviderConcurrency: map[string]int{"weather": 2},
+	}, provider)
+
+	for _, city := range []string{"Moscow", "Berlin", "Paris", "Rome"} {
+		_, err := queue.Enqueue(context.Background(), &dto.RefreshJobDto{DataType: "weather", Key: city})
+		require.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go worker.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		_, total := provider.stats()
+		return total == 4
+	}, 2*time.Second, 5*time.Millisecond)
+
+	peak, _ := provider.stats()
+	assert.Equal(t, 2, peak)
+}
+
+// sharedPauses stands in for the Redis pause store shared by replicas.
+type sharedPauses struct {
+	mu    sync.Mutex
+	all   bool
+	types []string
+}
+
+func (p *sharedPauses) Pause(ctx context.Context, dataType string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if dataType == "" {
+		p.all = true
+		return nil
+	}
+	p.types = append(p.types, dataType)
+	return nil
+}
+
+func (p *sharedPauses) Resume(ctx context.Context, dataType string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if dataType == "" {
+		p.all, p.types = false, nil
+		return nil
+	}
+	p.types = slices.DeleteFunc(p.types, func(t string) bool { return t == dataType })
+	return nil
+}
+
+func (p *sharedPauses) Paused(ctx context.Context) (bool, []string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.all, slices.Clone(p.types), nil
+}
+
+func TestPriorityScheduler_EnqueuesJobsWithQueue(t *testing.T) {
+	queue := newMemoryJobQueue()
+	provider := &recordingProvider{}
+	scheduler := newTestScheduler([]dto.PopularDataDto{
+		{ID: 1, DataType: "weather", Key: "Moscow"},
+		{ID: 2, DataType: "weather", Key: "Berlin"},
+	}, &config.SchedulerConfig{
+		ReloadInterval:         time.Hour,
+		DefaultRefreshInterval: time.Hour,
+		Workers:                1,
+		FetchTimeout:           time.Second,
+	}, provider)
+	scheduler.SetQueue(queue)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go scheduler.Start(ctx)
+
+	require.Eventually(t, func() bool { return len(queue.snapshot()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Empty(t, provider.calls())
+}
+
+func TestJobWorker_PublishesWithTheJobsFencingToken(t *testing.T) {
+	queue := newMemoryJobQueue()
+	broker := memory.NewBroker(1)
+	worker := newTestJobWorkerWithBroker(broker, queue, &config.JobQueueConfig{
+		Workers:           1,
+		PollInterval:      5 * time.Millisecond,
+		VisibilityTimeout: time.Minute,
+		FetchTimeout:      time.Second,
+		MaxAttempts:       1,
+	}, &recordingProvider{})
+
+	_, err := queue.Enqueue(context.Background(), &dto.RefreshJobDto{DataType: "weather", Key: "Moscow", Token: 7})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tokens := make(chan int64, 1)
+	serializer := messaging.NewJSONSerializer(messaging.EventModeStructured)
+	go broker.NewSubscriber("test").Run(ctx, []string{"events"}, func(ctx context.Context, msg *messaging.Message) error {
+		event, err := serializer.Deserialize(ctx, msg)
+		if err != nil {
+			return err
+		}
+		tokens <- event.FencingToken
+		return nil
+	})
+	go worker.Start(ctx)
+
+	select {
+	case token := <-tokens:
+		assert.Equal(t, int64(7), token)
+	case <-time.After(2 * time.Second):
+		t.Fatal("the job's result was not published")
+	}
+}
+
+func TestPriorityScheduler_EnqueuesJobsWithLeadersToken(t *testing.T) {
+	queue := newMemoryJobQueue()
+	scheduler := newTestScheduler([]dto.PopularDataDto{{ID: 1, DataType: "weather", Key: "Moscow"}}, &config.SchedulerConfig{
+		ReloadInterval:         time.Hour,
+		DefaultRefreshInterval: time.Hour,
+		Workers:                1,
+		FetchTimeout:           time.Second,
+	}, &recordingProvider{})
+	scheduler.SetQueue(queue)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go scheduler.Lead(ctx, 5)
+
+	require.Eventually(t, func() bool { return len(queue.snapshot()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int64(5), queue.snapshot()[0].Token)
+}
@@ -0,0 +1,130 @@
+package background
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// pauseRefresh bounds how long a pause made on another replica may go
+// unnoticed.
+const pauseRefresh = time.Second
+
+// PauseStore keeps the paused data types. An empty data type means all of
+// them.
+type PauseStore interface {
+	Pause(ctx context.Context, dataType string) error
+	Resume(ctx context.Context, dataType string) error
+	Paused(ctx context.Context) (all bool, types []string, err error)
+}
+
+type PauseState struct {
+	All   bool     `json:"all"`
+	Types []string `json:"types"`
+}
+
+// memoryPauses is the PauseStore of a single process, used until a shared
+// store is set.
+type memoryPauses struct {
+	mu    sync.Mutex
+	all   bool
+	types map[string]bool
+}
+
+func (m *memoryPauses) Pause(ctx context.Context, dataType string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if dataType == "" {
+		m.all = true
+		return nil
+	}
+	m.types[dataType] = true
+	return nil
+}
+
+func (m *memoryPauses) Resume(ctx context.Context, dataType string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if dataType == "" {
+		m.all = false
+		clear(m.types)
+		return nil
+	}
+	delete(m.types, dataType)
+	return nil
+}
+
+func (m *memoryPauses) Paused(ctx context.Context) (bool, []string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	types := make([]string, 0, len(m.types))
+	for t := range m.types {
+		types = append(types, t)
+	}
+	slices.Sort(types)
+	return m.all, types, nil
+}
+
+// pauses caches a PauseStore for pauseRefresh, so dispatching does not query
+// it for every item.
+type pauses struct {
+	mu     sync.Mutex
+	store  PauseStore
+	state  PauseState
+	loaded time.Time
+}
+
+func newPauses() *pauses {
+	return &pauses{store: &memoryPauses{types: make(map[string]bool)}}
+}
+
+func (p *pauses) setStore(store PauseStore) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.store = store
+	p.loaded = time.Time{}
+}
+
+func (p *pauses) pause(ctx context.Context, dataType string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.loaded = time.Time{}
+	return p.store.Pause(ctx, dataType)
+}
+
+func (p *pauses) resume(ctx context.Context, dataType string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.loaded = time.Time{}
+	return p.store.Resume(ctx, dataType)
+}
+
+func (p *pauses) get(ctx context.Context) (PauseState, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if time.Since(p.loaded) < pauseRefresh {
+		return p.state, nil
+	}
+
+	all, types, err := p.store.Paused(ctx)
+	if err != nil {
+		return p.state, err
+	}
+	if types == nil {
+		types = []string{}
+	}
+	p.state, p.loaded = PauseState{All: all, Types: types}, time.Now()
+	return p.state, nil
+}
+
+// paused reports whether dataType is paused. When the store cannot be read
+// the last known state applies.
+func (p *pauses) paused(ctx context.Context, dataType string) bool {
+	state, err := p.get(ctx)
+	if err != nil {
+		slog.Warn("failed to read scheduler pauses, using the last known state", "error", err)
+	}
+	return state.All || slices.Contains(state.Types, dataType)
+}
@@ -2,63 +2,379 @@ package background
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"service-info-aggregator/internal/config"
+	"service-info-aggregator/internal/metrics"
+	"service-info-aggregator/internal/model/dto"
+	"service-info-aggregator/internal/repository/refresh_jobs"
 	"service-info-aggregator/internal/service/aggregation"
 	"service-info-aggregator/internal/service/popular_data"
 )
 
+type refreshResult struct {
+	item *scheduledItem
+	// dataType is the type the refresh ran for; a reload may change the
+	// item's while it runs.
+	dataType string
+	trigger  string
+	started  time.Time
+	finished time.Time
+	err      error
+}
+
 type PriorityScheduler struct {
 	popularDataService *popular_data.PopularDataService
 	aggregationService *aggregation.AggregationService
-	interval           time.Duration
+	providers          map[string]aggregation.Provider
+	cfg                *config.SchedulerConfig
+	schedule           *schedule
+	filter             func(item dto.PopularDataDto) bool
+	queue              refresh_jobs.Repository
+	reloadRequested    chan struct{}
+	commands           chan func()
+	running            atomic.Bool
+
+	// stopped is closed when the current Start returns, so commands sent
+	// while the loop drains or after it ended do not wait forever.
+	stopMu  sync.Mutex
+	stopped chan struct{}
+
+	token atomic.Int64
+
+	inFlight   int
+	byProvider map[string]int
+	done       chan refreshResult
+	wg         sync.WaitGroup
+
+	pauses  *pauses
+	history *RunHistory
+
+	triggers      TriggerStore
+	triggerCursor string
 }
 
-func NewPriorityScheduler(ps *popular_data.PopularDataService, as *aggregation.AggregationService, interval time.Duration) *PriorityScheduler {
+func NewPriorityScheduler(ps *popular_data.PopularDataService, as *aggregation.AggregationService,
+	cfg *config.SchedulerConfig, providers ...aggregation.Provider) *PriorityScheduler {
+	byName := make(map[string]aggregation.Provider, len(providers))
+	for _, p := range providers {
+		byName[p.Name()] = p
+	}
+
 	return &PriorityScheduler{
 		popularDataService: ps,
 		aggregationService: as,
-		interval:           interval,
+		providers:          byName,
+		cfg:                cfg,
+		schedule:           newSchedule(),
+		byProvider:         make(map[string]int),
+		done:               make(chan refreshResult),
+		reloadRequested:    make(chan struct{}, 1),
+		commands:           make(chan func()),
+		pauses:             newPauses(),
+		history:            NewRunHistory(cfg.HistorySize),
 	}
 }
 
+// SetFilter restricts the scheduler to the items filter accepts, e.g. the
+// keys this replica owns when scheduling is sharded. Call it before Start.
+func (s *PriorityScheduler) SetFilter(filter func(item dto.PopularDataDto) bool) {
+	s.filter = filter
+}
+
+// SetQueue makes the scheduler enqueue due items as refresh jobs for the
+// JobWorkers instead of fetching them itself. Call it before Start.
+func (s *PriorityScheduler) SetQueue(queue refresh_jobs.Repository) {
+	s.queue = queue
+}
+
+// Reload asks the running scheduler to reload items from the database now
+// instead of at the next ReloadInterval.
+func (s *PriorityScheduler) Reload() {
+	select {
+	case s.reloadRequested <- struct{}{}:
+	default:
+	}
+}
+
+// Lead runs the scheduler for one leadership. Its refreshes carry token, so
+// the cache refuses them once a newer leader was elected.
+func (s *PriorityScheduler) Lead(ctx context.Context, token int64) {
+	s.token.Store(token)
+	defer s.token.Store(0)
+	s.Start(ctx)
+}
+
+// Start sleeps until the earliest item is due and hands due items to a pool of
+// Workers in priority order, respecting the per-provider limits. An item is
+// never queued again while its previous refresh is still running. Items are
+// reloaded from the database every ReloadInterval.
 func (s *PriorityScheduler) Start(ctx context.Context) {
-	ticker := time.NewTicker(s.interval)
-	defer ticker.Stop()
+	slog.Info("priority scheduler started",
+		"workers", s.cfg.Workers,
+		"reload_interval", s.cfg.ReloadInterval,
+		"default_refresh_interval", s.cfg.DefaultRefreshInterval)
+
+	stopped := make(chan struct{})
+	s.stopMu.Lock()
+	s.stopped = stopped
+	s.stopMu.Unlock()
+
+	s.running.Store(true)
+	defer func() {
+		s.running.Store(false)
+		close(stopped)
+	}()
+
+	s.reload(ctx)
+	lastReload := time.Now()
 
-	slog.Info("priority scheduler started", "interval", s.interval)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	// Triggers made while no replica ran the scheduler are dropped.
+	var pollTriggers <-chan time.Time
+	if s.triggers != nil {
+		s.triggerCursor = ""
+		s.applyTriggers(ctx)
+		ticker := time.NewTicker(triggerPoll)
+		defer ticker.Stop()
+		pollTriggers = ticker.C
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
+			s.drain()
 			slog.Info("priority scheduler stopped")
 			return
-		case <-ticker.C:
-			s.execute(ctx)
+		case res := <-s.done:
+			s.complete(res)
+		case <-s.reloadRequested:
+			lastReload = time.Time{}
+		case cmd := <-s.commands:
+			cmd()
+		case <-pollTriggers:
+			s.applyTriggers(ctx)
+		case <-timer.C:
 		}
+
+		if time.Since(lastReload) >= s.cfg.ReloadInterval {
+			s.reload(ctx)
+			lastReload = time.Now()
+		}
+
+		s.schedule.promoteDue(time.Now())
+		s.dispatch(ctx)
+
+		timer.Reset(s.wait(lastReload))
 	}
 }
 
-func (s *PriorityScheduler) execute(ctx context.Context) {
+func (s *PriorityScheduler) wait(lastReload time.Time) time.Duration {
+	wait := time.Until(lastReload.Add(s.cfg.ReloadInterval))
+	if next, ok := s.schedule.nextRun(); ok {
+		wait = min(wait, time.Until(next))
+	}
+	return max(wait, 0)
+}
+
+func (s *PriorityScheduler) reload(ctx context.Context) {
 	items, err := s.popularDataService.GetAll(ctx)
 	if err != nil {
 		slog.Error("failed to fetch popular data items", "err", err)
 		return
 	}
 
-	for _, item := range items {
-		switch item.DataType {
-		case "weather":
-			_, err := s.aggregationService.Execute(ctx, &aggregation.WeatherProvider{}, item.Key)
-			if err != nil {
-				slog.Error("aggregation failed",
-					"type", item.DataType,
-					"key", item.Key,
-					"error", err)
+	if s.filter != nil {
+		owned := make([]dto.PopularDataDto, 0, len(items))
+		for _, item := range items {
+			if s.filter(item) {
+				owned = append(owned, item)
 			}
-		default:
-			slog.Warn("unknown data type", "type", item.DataType)
 		}
+		items = owned
+	}
+	s.schedule.sync(items, time.Now(), timingOf)
+}
+
+// dispatch starts ready items while workers are free. Items whose provider is
+// at its limit stay ready, so lower-priority items of other providers can
+// use the free workers meanwhile.
+func (s *PriorityScheduler) dispatch(ctx context.Context) {
+	var blocked []*scheduledItem
+	defer func() {
+		for _, it := range blocked {
+			s.schedule.requeue(it)
+		}
+	}()
+
+	for s.inFlight < max(s.cfg.Workers, 1) {
+		it, ok := s.schedule.popReady()
+		if !ok {
+			return
+		}
+
+		// Ownership may have changed since the last reload, e.g. when this
+		// replica's membership expired.
+		if s.filter != nil && !s.filter(it.item) {
+			s.schedule.remove(it)
+			continue
+		}
+
+		dataType := it.item.DataType
+		if !it.forced && s.pauses.paused(ctx, dataType) {
+			s.schedule.reschedule(it, it.timing.Next(time.Now(), s.refreshInterval(it)))
+			continue
+		}
+		// Queued refreshes are limited by the JobWorkers that run them.
+		if limit := s.cfg.ProviderConcurrency[dataType]; s.queue == nil && limit > 0 && s.byProvider[dataType] >= limit {
+			blocked = append(blocked, it)
+			continue
+		}
+
+		trigger := triggerScheduled
+		if it.forced {
+			trigger = triggerManual
+			it.forced = false
+		}
+
+		s.inFlight++
+		s.byProvider[dataType]++
+		metrics.SchedulerInFlight.Inc()
+
+		// The goroutine gets a copy: reloads update it.item meanwhile.
+		item := it.item
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			started := time.Now()
+			err := s.refresh(ctx, item, trigger)
+			s.done <- refreshResult{item: it, dataType: dataType, trigger: trigger, started: started, finished: time.Now(), err: err}
+		}()
+	}
+}
+
+func (s *PriorityScheduler) complete(res refreshResult) {
+	it := res.item
+	s.inFlight--
+	s.byProvider[res.dataType]--
+	metrics.SchedulerInFlight.Dec()
+
+	interval := s.refreshInterval(it)
+	if s.queue != nil {
+		// Only the job was enqueued; the JobWorker running it records the
+		// outcome.
+		s.schedule.reschedule(it, it.timing.Next(res.started, interval))
+		return
+	}
+
+	duration := res.finished.Sub(res.started)
+	metrics.SchedulerRefreshDuration.WithLabelValues(it.item.DataType).Observe(duration.Seconds())
+
+	run := RunRecord{
+		ItemID:     it.item.ID,
+		DataType:   it.item.DataType,
+		Key:        it.item.Key,
+		Trigger:    res.trigger,
+		Started:    res.started,
+		DurationMs: duration.Milliseconds(),
+		Outcome:    outcomeSuccess,
+	}
+	if res.err != nil {
+		run.Outcome, run.Error = outcomeError, res.err.Error()
+	}
+	s.history.Record(run)
+
+	// The next run is counted from the start of this one. A refresh that
+	// overran it is counted from its end instead, so a provider slower than
+	// the interval still gets a full interval of rest and missed runs are not
+	// replayed.
+	next := it.timing.Next(res.started, interval)
+	if next.Before(res.finished) {
+		next = it.timing.Next(res.finished, interval)
+		metrics.SchedulerOverruns.WithLabelValues(it.item.DataType).Inc()
+		slog.Warn("scheduled refresh overran its interval",
+			"type", it.item.DataType,
+			"key", it.item.Key,
+			"duration", duration,
+			"next_run", next)
+	}
+	s.schedule.reschedule(it, next)
+}
+
+// drain waits for the running refreshes, whose context is already cancelled,
+// so Start can be called again later, e.g. on regaining leadership.
+func (s *PriorityScheduler) drain() {
+	for s.inFlight > 0 {
+		s.complete(<-s.done)
+	}
+}
+
+func (s *PriorityScheduler) refresh(ctx context.Context, item dto.PopularDataDto, trigger string) error {
+	if s.queue != nil {
+		return s.enqueue(ctx, item, trigger == triggerManual)
+	}
+
+	provider, ok := s.providers[item.DataType]
+	if !ok {
+		slog.Warn("unknown data type", "type", item.DataType)
+		return fmt.Errorf("unknown data type: %s", item.DataType)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.FetchTimeout)
+	defer cancel()
+
+	if _, err := s.aggregationService.ExecuteFenced(ctx, provider, item.Key, s.token.Load()); err != nil {
+		slog.Error("aggregation failed",
+			"type", item.DataType,
+			"key", item.Key,
+			"error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *PriorityScheduler) enqueue(ctx context.Context, item dto.PopularDataDto, manual bool) error {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.FetchTimeout)
+	defer cancel()
+
+	added, err := s.queue.Enqueue(ctx, &dto.RefreshJobDto{
+		DataType: item.DataType,
+		Key:      item.Key,
+		Priority: item.Priority,
+		Manual:   manual,
+		Token:    s.token.Load(),
+	})
+	if err != nil {
+		slog.Error("failed to enqueue refresh job",
+			"type", item.DataType,
+			"key", item.Key,
+			"error", err)
+		return err
+	}
+	if added {
+		metrics.RefreshJobsEnqueued.WithLabelValues(item.DataType).Inc()
+	}
+	return nil
+}
+
+func (s *PriorityScheduler) refreshInterval(it *scheduledItem) time.Duration {
+	if it.item.RefreshIntervalSeconds > 0 {
+		return time.Duration(it.item.RefreshIntervalSeconds) * time.Second
+	}
+	return s.cfg.DefaultRefreshInterval
+}
+
+func timingOf(item dto.PopularDataDto) *popular_data.Timing {
+	timing, err := popular_data.ParseTiming(&item)
+	if err != nil {
+		slog.Error("invalid refresh timing, using the refresh interval", "id", item.ID, "error", err)
+		timing, _ = popular_data.ParseTiming(&dto.PopularDataDto{})
 	}
+	return timing
 }
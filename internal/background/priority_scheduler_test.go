@@ -0,0 +1,495 @@
+package background_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"service-info-aggregator/internal/background"
+	"service-info-aggregator/internal/config"
+	"service-info-aggregator/internal/coordination"
+	"service-info-aggregator/internal/messaging"
+	"service-info-aggregator/internal/messaging/memory"
+	"service-info-aggregator/internal/metrics"
+	"service-info-aggregator/internal/model/dto"
+	popularDataRepo "service-info-aggregator/internal/repository/popular_data"
+	"service-info-aggregator/internal/service/aggregation"
+	"service-info-aggregator/internal/service/popular_data"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRepository keeps popular data items in creation order. Items given
+// with an ID keep it.
+type memoryRepository struct {
+	mu     sync.Mutex
+	nextID int
+	items  []dto.PopularDataDto
+}
+
+func newMemoryRepository(items ...dto.PopularDataDto) *memoryRepository {
+	r := &memoryRepository{}
+	r.set(items)
+	return r
+}
+
+func (r *memoryRepository) set(items []dto.PopularDataDto) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items = nil
+	for _, item := range items {
+		r.add(item)
+	}
+}
+
+func (r *memoryRepository) add(item dto.PopularDataDto) dto.PopularDataDto {
+	if item.ID == 0 {
+		item.ID = r.nextID + 1
+	}
+	r.nextID = max(r.nextID, item.ID)
+	r.items = append(r.items, item)
+	return item
+}
+
+func (r *memoryRepository) Create(ctx context.Context, d *dto.PopularDataDto) (*dto.PopularDataDto, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	created := r.add(*d)
+	return &created, nil
+}
+
+func (r *memoryRepository) GetAll(ctx context.Context) ([]dto.PopularDataDto, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]dto.PopularDataDto(nil), r.items...), nil
+}
+
+func (r *memoryRepository) GetById(ctx context.Context, id int) (*dto.PopularDataDto, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, item := range r.items {
+		if item.ID == id {
+			return &item, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryRepository) Update(ctx context.Context, id int, d *dto.PopularDataDto) (*dto.PopularDataDto, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	updated := *d
+	updated.ID = id
+	for i, item := range r.items {
+		if item.ID == id {
+			r.items[i] = updated
+		}
+	}
+	return &updated, nil
+}
+
+func (r *memoryRepository) Delete(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, item := range r.items {
+		if item.ID == id {
+			r.items = append(r.items[:i], r.items[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) keys() []string {
+	items, _ := r.GetAll(context.Background())
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		keys = append(keys, item.Key)
+	}
+	return keys
+}
+
+type recordingProvider struct {
+	mu      sync.Mutex
+	fetched []string
+}
+
+func (p *recordingProvider) Name() string { return "weather" }
+
+func (p *recordingProvider) CacheKey(param string) string { return "weather:" + param }
+
+func (p *recordingProvider) Fetch(ctx context.Context, param string) (any, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fetched = append(p.fetched, param)
+	return map[string]string{"city": param}, nil
+}
+
+func (p *recordingProvider) calls() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.fetched...)
+}
+
+type slowProvider struct {
+	delay   time.Duration
+	mu      sync.Mutex
+	running int
+	peak    int
+	total   int
+}
+
+func (p *slowProvider) Name() string { return "weather" }
+
+func (p *slowProvider) CacheKey(param string) string { return "weather:" + param }
+
+func (p *slowProvider) Fetch(ctx context.Context, param string) (any, error) {
+	p.mu.Lock()
+	p.running++
+	p.peak = max(p.peak, p.running)
+	p.mu.Unlock()
+
+	select {
+	case <-time.After(p.delay):
+	case <-ctx.Done():
+	}
+
+	p.mu.Lock()
+	p.running--
+	p.total++
+	p.mu.Unlock()
+	return param, ctx.Err()
+}
+
+func (p *slowProvider) stats() (peak, total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.peak, p.total
+}
+
+func newTestScheduler(items []dto.PopularDataDto, cfg *config.SchedulerConfig, provider aggregation.Provider) *background.PriorityScheduler {
+	return newTestSchedulerWithRepository(newMemoryRepository(items...), cfg, provider)
+}
+
+func newTestSchedulerWithRepository(repo *memoryRepository, cfg *config.SchedulerConfig, provider aggregation.Provider) *background.PriorityScheduler {
+	broker := memory.NewBroker(1)
+	aggService := aggregation.NewAggregationService(broker, messaging.NewJSONSerializer(messaging.EventModeStructured),
+		messaging.NewTopicRoutes("events", nil), "", "/test")
+	service := popular_data.NewPopularDataService(repo)
+
+	return background.NewPriorityScheduler(service, aggService, cfg, provider)
+}
+
+func TestPriorityScheduler_RunsDueItemsByPriority(t *testing.T) {
+	provider := &recordingProvider{}
+	scheduler := newTestScheduler([]dto.PopularDataDto{
+		{ID: 1, DataType: "weather", Key: "Low", Priority: 1},
+		{ID: 2, DataType: "weather", Key: "High", Priority: 5},
+		{ID: 3, DataType: "weather", Key: "Mid", Priority: 3},
+	}, &config.SchedulerConfig{
+		ReloadInterval:         time.Hour,
+		DefaultRefreshInterval: time.Hour,
+		Workers:                1,
+		FetchTimeout:           time.Second,
+	}, provider)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go scheduler.Start(ctx)
+
+	require.Eventually(t, func() bool { return len(provider.calls()) == 3 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"High", "Mid", "Low"}, provider.calls())
+}
+
+func TestPriorityScheduler_UsesPerItemInterval(t *testing.T) {
+	provider := &recordingProvider{}
+	scheduler := newTestScheduler([]dto.PopularDataDto{
+		{ID: 1, DataType: "weather", Key: "Fast", RefreshIntervalSeconds: 1},
+		{ID: 2, DataType: "weather", Key: "Slow"},
+	}, &config.SchedulerConfig{
+		ReloadInterval:         time.Hour,
+		DefaultRefreshInterval: time.Hour,
+		Workers:                10,
+		FetchTimeout:           time.Second,
+	}, provider)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go scheduler.Start(ctx)
+
+	require.Eventually(t, func() bool { return len(provider.calls()) == 3 }, 3*time.Second, 10*time.Millisecond)
+	assert.ElementsMatch(t, []string{"Fast", "Slow", "Fast"}, provider.calls())
+}
+
+func TestPriorityScheduler_LimitsProviderConcurrency(t *testing.T) {
+	provider := &slowProvider{delay: 20 * time.Millisecond}
+	scheduler := newTestScheduler([]dto.PopularDataDto{
+		{ID: 1, DataType: "weather", Key: "Moscow"},
+		{ID: 2, DataType: "weather", Key: "Berlin"},
+		{ID: 3, DataType: "weather", Key: "Paris"},
+	}, &config.SchedulerConfig{
+		ReloadInterval:         time.Hour,
+		DefaultRefreshInterval: time.Hour,
+		Workers:                3,
+		FetchTimeout:           time.Second,
+		ProviderConcurrency:    map[string]int{"weather": 2},
+	}, provider)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go scheduler.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		_, total := provider.stats()
+		return total == 3
+	}, time.Second, 5*time.Millisecond)
+
+	peak, _ := provider.stats()
+	assert.Equal(t, 2, peak)
+}
+
+func TestPriorityScheduler_OverrunWaitsAnIntervalAfterTimeout(t *testing.T) {
+	provider := &slowProvider{delay: time.Second}
+	scheduler := newTestScheduler([]dto.PopularDataDto{
+		{ID: 1, DataType: "weather", Key: "Moscow"},
+	}, &config.SchedulerConfig{
+		ReloadInterval:         time.Hour,
+		DefaultRefreshInterval: 100 * time.Millisecond,
+		Workers:                1,
+		FetchTimeout:           150 * time.Millisecond,
+		HistorySize:            10,
+	}, provider)
+	overruns := testutil.ToFloat64(metrics.SchedulerOverruns.WithLabelValues("weather"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go scheduler.Start(ctx)
+
+	require.Eventually(t, func() bool { return len(scheduler.Runs(10)) >= 3 }, 3*time.Second, 10*time.Millisecond)
+	cancel()
+
+	runs := scheduler.Runs(3)
+	for _, run := range runs {
+		// FetchTimeout cuts every run short of the provider's delay.
+		assert.Equal(t, "error", run.Outcome)
+		assert.Contains(t, run.Error, context.DeadlineExceeded.Error())
+		assert.Less(t, run.DurationMs, int64(500))
+	}
+	// Runs are newest first. Each one starts an interval after the previous
+	// one timed out, not right away.
+	for i := 0; i < len(runs)-1; i++ {
+		gap := runs[i].Started.Sub(runs[i+1].Started)
+		assert.GreaterOrEqual(t, gap, 230*time.Millisecond)
+	}
+	assert.GreaterOrEqual(t, testutil.ToFloat64(metrics.SchedulerOverruns.WithLabelValues("weather"))-overruns, 2.0)
+}
+
+func TestPriorityScheduler_FilterAndReload(t *testing.T) {
+	provider := &recordingProvider{}
+	scheduler := newTestScheduler([]dto.PopularDataDto{
+		{ID: 1, DataType: "weather", Key: "Moscow"},
+		{ID: 2, DataType: "weather", Key: "Berlin"},
+	}, &config.SchedulerConfig{
+		ReloadInterval:         time.Hour,
+		DefaultRefreshInterval: time.Hour,
+		Workers:                1,
+		FetchTimeout:           time.Second,
+	}, provider)
+
+	var owned sync.Map
+	owned.Store("Moscow", true)
+	scheduler.SetFilter(func(item dto.PopularDataDto) bool {
+		_, ok := owned.Load(item.Key)
+		return ok
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go scheduler.Start(ctx)
+
+	require.Eventually(t, func() bool { return len(provider.calls()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"Moscow"}, provider.calls())
+
+	owned.Store("Berlin", true)
+	scheduler.Reload()
+
+	require.Eventually(t, func() bool { return len(provider.calls()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"Moscow", "Berlin"}, provider.calls())
+}
+
+func TestPriorityScheduler_PauseTriggerAndRuns(t *testing.T) {
+	provider := &recordingProvider{}
+	scheduler := newTestScheduler([]dto.PopularDataDto{
+		{ID: 1, DataType: "weather", Key: "Moscow"},
+		{ID: 2, DataType: "weather", Key: "Berlin"},
+	}, &config.SchedulerConfig{
+		ReloadInterval:         time.Hour,
+		DefaultRefreshInterval: time.Hour,
+		Workers:                1,
+		FetchTimeout:           time.Second,
+		HistorySize:            10,
+	}, provider)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	assert.ErrorIs(t, scheduler.Trigger(ctx, 1), background.ErrNotRunning)
+
+	require.NoError(t, scheduler.Pause(ctx, "weather"))
+	paused, err := scheduler.Paused(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, background.PauseState{Types: []string{"weather"}}, paused)
+
+	go scheduler.Start(ctx)
+	require.Eventually(t, scheduler.Running, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, scheduler.Trigger(ctx, 2))
+	require.Eventually(t, func() bool { return len(provider.calls()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"Berlin"}, provider.calls())
+	assert.ErrorIs(t, scheduler.Trigger(ctx, 42), background.ErrItemNotFound)
+
+	n, err := scheduler.TriggerAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	require.Eventually(t, func() bool { return len(scheduler.Runs(10)) == 3 }, time.Second, 5*time.Millisecond)
+
+	runs := scheduler.Runs(10)
+	assert.Equal(t, "Berlin", runs[2].Key)
+	for _, run := range runs {
+		assert.Equal(t, "manual", run.Trigger)
+		assert.Equal(t, "success", run.Outcome)
+	}
+
+	require.NoError(t, scheduler.Resume(ctx, ""))
+	paused, err = scheduler.Paused(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, background.PauseState{Types: []string{}}, paused)
+}
+
+func TestPriorityScheduler_HandleChange(t *testing.T) {
+	provider := &recordingProvider{}
+	repo := newMemoryRepository(dto.PopularDataDto{ID: 1, DataType: "weather", Key: "Moscow"})
+	scheduler := newTestSchedulerWithRepository(repo, &config.SchedulerConfig{
+		ReloadInterval:         time.Hour,
+		DefaultRefreshInterval: time.Hour,
+		Workers:                1,
+		FetchTimeout:           time.Second,
+	}, provider)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go scheduler.Start(ctx)
+
+	require.Eventually(t, func() bool { return len(provider.calls()) == 1 }, time.Second, 5*time.Millisecond)
+
+	repo.set([]dto.PopularDataDto{
+		{ID: 1, DataType: "weather", Key: "Moscow"},
+		{ID: 2, DataType: "weather", Key: "Berlin"},
+	})
+	scheduler.HandleChange(ctx, popularDataRepo.Change{Op: "INSERT", ID: 2})
+
+	require.Eventually(t, func() bool { return len(provider.calls()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"Moscow", "Berlin"}, provider.calls())
+
+	scheduler.HandleChange(ctx, popularDataRepo.Change{Op: "DELETE", ID: 2})
+	assert.ErrorIs(t, scheduler.Trigger(ctx, 2), background.ErrItemNotFound)
+	assert.NoError(t, scheduler.Trigger(ctx, 1))
+}
+
+// blockingProvider ignores cancellation until released, like a provider stuck
+// in a slow call.
+type blockingProvider struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (p *blockingProvider) Name() string { return "weather" }
+
+func (p *blockingProvider) CacheKey(param string) string { return "weather:" + param }
+
+func (p *blockingProvider) Fetch(ctx context.Context, param string) (any, error) {
+	p.started <- struct{}{}
+	<-p.release
+	return param, nil
+}
+
+func TestPriorityScheduler_HandleChangeReturnsWhenStartExits(t *testing.T) {
+	provider := &blockingProvider{started: make(chan struct{}, 1), release: make(chan struct{})}
+	repo := newMemoryRepository(dto.PopularDataDto{ID: 1, DataType: "weather", Key: "Moscow"})
+	scheduler := newTestSchedulerWithRepository(repo, &config.SchedulerConfig{
+		ReloadInterval:         time.Hour,
+		DefaultRefreshInterval: time.Hour,
+		Workers:                1,
+		FetchTimeout:           time.Second,
+	}, provider)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		scheduler.Start(ctx)
+		close(stopped)
+	}()
+	<-provider.started
+
+	// Start is now draining the stuck refresh and no longer takes commands.
+	cancel()
+	applied := make(chan struct{})
+	go func() {
+		scheduler.HandleChange(context.Background(), popularDataRepo.Change{Op: "UPDATE", ID: 1})
+		close(applied)
+	}()
+
+	close(provider.release)
+	<-stopped
+	select {
+	case <-applied:
+	case <-time.After(time.Second):
+		t.Fatal("HandleChange is still blocked after Start returned")
+	}
+	assert.ErrorIs(t, scheduler.Trigger(context.Background(), 1), background.ErrNotRunning)
+}
+
+func TestPriorityScheduler_StopsRunningKeysWhenMembershipExpires(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	const ttl = 200 * time.Millisecond
+	membership := coordination.NewMembership(client, "scheduler:members", "a", 20*time.Millisecond, ttl, 64)
+
+	provider := &recordingProvider{}
+	scheduler := newTestScheduler([]dto.PopularDataDto{
+		{ID: 1, DataType: "weather", Key: "Moscow"},
+		{ID: 2, DataType: "weather", Key: "Berlin"},
+	}, &config.SchedulerConfig{
+		ReloadInterval:         time.Hour,
+		DefaultRefreshInterval: 20 * time.Millisecond,
+		Workers:                2,
+		FetchTimeout:           time.Second,
+	}, provider)
+	scheduler.SetFilter(func(item dto.PopularDataDto) bool {
+		return membership.Owns(item.DataType + ":" + item.Key)
+	})
+	membership.OnChange(scheduler.Reload)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go membership.Run(ctx)
+	require.Eventually(t, func() bool { return membership.Owns("weather:Moscow") }, time.Second, 5*time.Millisecond)
+	go scheduler.Start(ctx)
+	require.Eventually(t, func() bool { return len(provider.calls()) >= 4 }, time.Second, 5*time.Millisecond)
+
+	// Heartbeats fail from now on; within the TTL the other replicas take
+	// the keys over, so this one has to stop refreshing them.
+	server.Close()
+	time.Sleep(ttl + 50*time.Millisecond)
+
+	stopped := len(provider.calls())
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, stopped, len(provider.calls()))
+}
@@ -0,0 +1,133 @@
+package background
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"service-info-aggregator/internal/config"
+	"service-info-aggregator/internal/model/dto"
+	"service-info-aggregator/internal/repository/popularity"
+	"service-info-aggregator/internal/service/popular_data"
+)
+
+// Promoter adds keys whose decayed request score crosses PromoteThreshold to
+// popular_data and removes the items it added once their score falls below
+// DemoteThreshold. One replica runs each round.
+type Promoter struct {
+	popularity         *popularity.RedisRepository
+	popularDataService *popular_data.PopularDataService
+	cfg                *config.PopularityConfig
+	instanceID         string
+	onChange           func()
+}
+
+func NewPromoter(repo *popularity.RedisRepository, ps *popular_data.PopularDataService,
+	cfg *config.PopularityConfig, instanceID string, onChange func()) *Promoter {
+	return &Promoter{
+		popularity:         repo,
+		popularDataService: ps,
+		cfg:                cfg,
+		instanceID:         instanceID,
+		onChange:           onChange,
+	}
+}
+
+func (p *Promoter) Start(ctx context.Context) {
+	ticker := time.NewTicker(p.cfg.PromoteInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			locked, err := p.popularity.TryLock(ctx, p.instanceID, p.cfg.PromoteInterval)
+			if err != nil {
+				slog.Error("failed to take popularity lock", "error", err)
+				continue
+			}
+			if !locked {
+				continue
+			}
+			if err := p.Run(ctx); err != nil {
+				slog.Error("popularity promotion failed", "error", err)
+			}
+		}
+	}
+}
+
+// Run performs one promotion round.
+func (p *Promoter) Run(ctx context.Context) error {
+	if err := p.popularity.UpdateScores(ctx); err != nil {
+		return err
+	}
+
+	items, err := p.popularDataService.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]bool, len(items))
+	var promoted []dto.PopularDataDto
+	var promotedKeys []string
+	for _, item := range items {
+		k := item.DataType + ":" + item.Key
+		existing[k] = true
+		if item.AutoPromoted {
+			promoted = append(promoted, item)
+			promotedKeys = append(promotedKeys, k)
+		}
+	}
+
+	changed := false
+	active := len(promoted)
+
+	scores, err := p.popularity.ScoresOf(ctx, promotedKeys)
+	if err != nil {
+		return err
+	}
+	for i, item := range promoted {
+		score := scores[promotedKeys[i]]
+		if score >= p.cfg.DemoteThreshold {
+			continue
+		}
+		if err := p.popularDataService.Delete(ctx, item.ID); err != nil {
+			return err
+		}
+		slog.Info("popular key demoted", "type", item.DataType, "key", item.Key, "score", score)
+		changed = true
+		active--
+		delete(existing, promotedKeys[i])
+	}
+
+	top, err := p.popularity.Top(ctx, p.cfg.MaxPromoted)
+	if err != nil {
+		return err
+	}
+	for _, s := range top {
+		if active >= p.cfg.MaxPromoted || s.Score < p.cfg.PromoteThreshold {
+			break
+		}
+		if existing[s.DataType+":"+s.Key] {
+			continue
+		}
+
+		_, err := p.popularDataService.Create(ctx, &dto.PopularDataDto{
+			DataType:     s.DataType,
+			Key:          s.Key,
+			AutoPromoted: true,
+		})
+		if err != nil {
+			return err
+		}
+		slog.Info("popular key promoted", "type", s.DataType, "key", s.Key, "score", s.Score)
+		changed = true
+		active++
+	}
+
+	if changed && p.onChange != nil {
+		p.onChange()
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package background_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"service-info-aggregator/internal/background"
+	"service-info-aggregator/internal/config"
+	"service-info-aggregator/internal/model/dto"
+	"service-info-aggregator/internal/repository/popularity"
+	"service-info-aggregator/internal/service/popular_data"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromoter_PromotesHotAndDemotesColdKeys(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	popularityRepo := popularity.NewRedisRepository(client, time.Hour, 6, 0.5)
+	repo := newMemoryRepository(
+		dto.PopularDataDto{DataType: "weather", Key: "Manual"},
+		dto.PopularDataDto{DataType: "weather", Key: "Cold", AutoPromoted: true},
+	)
+
+	ctx := context.Background()
+	for range 5 {
+		require.NoError(t, popularityRepo.Record(ctx, "weather", "Hot"))
+		require.NoError(t, popularityRepo.Record(ctx, "weather", "Manual"))
+	}
+	require.NoError(t, popularityRepo.Record(ctx, "weather", "Warm"))
+
+	reloads := 0
+	promoter := background.NewPromoter(popularityRepo, popular_data.NewPopularDataService(repo), &config.PopularityConfig{
+		PromoteThreshold: 3,
+		DemoteThreshold:  1,
+		MaxPromoted:      10,
+	}, "test", func() { reloads++ })
+
+	require.NoError(t, promoter.Run(ctx))
+
+	assert.Equal(t, []string{"Manual", "Hot"}, repo.keys())
+	assert.Equal(t, 1, reloads)
+
+	require.NoError(t, promoter.Run(ctx))
+	assert.Equal(t, []string{"Manual", "Hot"}, repo.keys())
+	assert.Equal(t, 1, reloads)
+}
@@ -0,0 +1,211 @@
+package background
+
+import (
+	"container/heap"
+	"time"
+
+	"service-info-aggregator/internal/model/dto"
+	"service-info-aggregator/internal/service/popular_data"
+)
+
+type scheduledItem struct {
+	item    dto.PopularDataDto
+	timing  *popular_data.Timing
+	nextRun time.Time
+	ready   bool
+	forced  bool
+	index   int
+}
+
+// dueQueue orders waiting items by their next run time.
+type dueQueue []*scheduledItem
+
+func (q dueQueue) Len() int           { return len(q) }
+func (q dueQueue) Less(i, j int) bool { return q[i].nextRun.Before(q[j].nextRun) }
+func (q dueQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index, q[j].index = i, j
+}
+func (q *dueQueue) Push(x any) {
+	it := x.(*scheduledItem)
+	it.index = len(*q)
+	*q = append(*q, it)
+}
+func (q *dueQueue) Pop() any {
+	old := *q
+	it := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	it.index = -1
+	return it
+}
+
+// readyQueue orders due items by priority, the longest overdue first on ties.
+type readyQueue []*scheduledItem
+
+func (q readyQueue) Len() int { return len(q) }
+func (q readyQueue) Less(i, j int) bool {
+	if q[i].item.Priority != q[j].item.Priority {
+		return q[i].item.Priority > q[j].item.Priority
+	}
+	return q[i].nextRun.Before(q[j].nextRun)
+}
+func (q readyQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index, q[j].index = i, j
+}
+func (q *readyQueue) Push(x any) {
+	it := x.(*scheduledItem)
+	it.index = len(*q)
+	*q = append(*q, it)
+}
+func (q *readyQueue) Pop() any {
+	old := *q
+	it := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	it.index = -1
+	return it
+}
+
+// schedule tracks every popular_data item in exactly one place: the due
+// queue while waiting, the ready queue once due, or neither while running.
+type schedule struct {
+	items map[int]*scheduledItem
+	due   dueQueue
+	ready readyQueue
+}
+
+func newSchedule() *schedule {
+	return &schedule{items: make(map[int]*scheduledItem)}
+}
+
+// sync reconciles the schedule with the rows loaded from the database. New
+// items get their first run time from their timing; existing ones keep their
+// next run time unless their timing changed while they were waiting.
+func (s *schedule) sync(items []dto.PopularDataDto, now time.Time, timingOf func(dto.PopularDataDto) *popular_data.Timing) {
+	seen := make(map[int]bool, len(items))
+	for _, item := range items {
+		seen[item.ID] = true
+		s.upsert(item, now, timingOf)
+	}
+
+	for id, it := range s.items {
+		if seen[id] {
+			continue
+		}
+		s.remove(it)
+	}
+}
+
+// upsert adds a new item or applies the changes of a known one.
+func (s *schedule) upsert(item dto.PopularDataDto, now time.Time, timingOf func(dto.PopularDataDto) *popular_data.Timing) {
+	it, ok := s.items[item.ID]
+	if !ok {
+		it = &scheduledItem{item: item, timing: timingOf(item)}
+		it.nextRun = it.timing.First(now)
+		s.items[item.ID] = it
+		heap.Push(&s.due, it)
+		return
+	}
+
+	retimed := timingChanged(it.item, item)
+	it.item = item
+	if retimed {
+		it.timing = timingOf(item)
+	}
+
+	switch {
+	case it.index < 0:
+	case it.ready:
+		heap.Fix(&s.ready, it.index)
+	case retimed:
+		it.nextRun = it.timing.First(now)
+		heap.Fix(&s.due, it.index)
+	}
+}
+
+func (s *schedule) remove(it *scheduledItem) {
+	delete(s.items, it.item.ID)
+	if it.index < 0 {
+		return
+	}
+	if it.ready {
+		heap.Remove(&s.ready, it.index)
+	} else {
+		heap.Remove(&s.due, it.index)
+	}
+}
+
+// promoteDue moves every item whose next run time has passed to the ready
+// queue.
+func (s *schedule) promoteDue(now time.Time) {
+	for s.due.Len() > 0 && !s.due[0].nextRun.After(now) {
+		it := heap.Pop(&s.due).(*scheduledItem)
+		it.ready = true
+		heap.Push(&s.ready, it)
+	}
+}
+
+// popReady hands out the highest-priority due item. The caller must pass it
+// back to reschedule once it has run.
+func (s *schedule) popReady() (*scheduledItem, bool) {
+	if s.ready.Len() == 0 {
+		return nil, false
+	}
+	it := heap.Pop(&s.ready).(*scheduledItem)
+	it.ready = false
+	return it, true
+}
+
+// requeue returns a due item that could not be started yet.
+func (s *schedule) requeue(it *scheduledItem) {
+	if s.items[it.item.ID] != it {
+		return
+	}
+	it.ready = true
+	heap.Push(&s.ready, it)
+}
+
+func (s *schedule) reschedule(it *scheduledItem, next time.Time) {
+	// Deleted while it was running.
+	if s.items[it.item.ID] != it {
+		return
+	}
+	it.nextRun = next
+	heap.Push(&s.due, it)
+}
+
+// trigger makes a waiting item due at now and marks it to run even if its
+// type is paused.
+func (s *schedule) trigger(id int, now time.Time) bool {
+	it, ok := s.items[id]
+	if !ok {
+		return false
+	}
+	if it.index < 0 {
+		return true
+	}
+
+	it.forced = true
+	if !it.ready {
+		it.nextRun = now
+		heap.Fix(&s.due, it.index)
+	}
+	return true
+}
+
+func (s *schedule) nextRun() (time.Time, bool) {
+	if s.due.Len() == 0 {
+		return time.Time{}, false
+	}
+	return s.due[0].nextRun, true
+}
+
+func timingChanged(a, b dto.PopularDataDto) bool {
+	return a.RefreshIntervalSeconds != b.RefreshIntervalSeconds ||
+		a.CronExpression != b.CronExpression ||
+		a.ActiveFrom != b.ActiveFrom ||
+		a.ActiveTo != b.ActiveTo ||
+		a.Timezone != b.Timezone
+}
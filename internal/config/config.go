@@ -1,48 +1,309 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type RedisConfig struct {
-	Addr         string
-	Username     string
-	Password     string
-	DB           int
-	MaxRetries   int
-	DialTimeout  time.Duration
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	WeatherTTL   time.Duration
+	Addr          string
+	Username      string
+	Password      string
+	DB            int
+	MaxRetries    int
+	DialTimeout   time.Duration
+	ReadTimeout   time.Duration
+	WriteTimeout  time.Duration
+	WeatherTTL    time.Duration
+	EventDedupTTL time.Duration
 }
 
 func NewRedisConfig() *RedisConfig {
 	return &RedisConfig{
-		Addr:         getEnv("REDIS_ADDR", "127.0.0.1:6379"),
-		Username:     getEnv("REDIS_USERNAME", ""),
-		Password:     getEnv("REDIS_PASSWORD", ""),
-		MaxRetries:   getEnvInt("REDIS_MAX_RETRIES", 3),
-		DB:           getEnvInt("REDIS_DB", 0),
-		DialTimeout:  getEnvDuration("REDIS_DIAL_TIMEOUT", 5*time.Second),
-		ReadTimeout:  getEnvDuration("REDIS_READ_TIMEOUT", 5*time.Second),
-		WriteTimeout: getEnvDuration("REDIS_WRITE_TIMEOUT", 5*time.Second),
-		WeatherTTL:   getEnvDuration("REDIS_WEATHER_TTL", 3000*time.Second),
+		Addr:          getEnv("REDIS_ADDR", "127.0.0.1:6379"),
+		Username:      getEnv("REDIS_USERNAME", ""),
+		Password:      getEnv("REDIS_PASSWORD", ""),
+		MaxRetries:    getEnvInt("REDIS_MAX_RETRIES", 3),
+		DB:            getEnvInt("REDIS_DB", 0),
+		DialTimeout:   getEnvDuration("REDIS_DIAL_TIMEOUT", 5*time.Second),
+		ReadTimeout:   getEnvDuration("REDIS_READ_TIMEOUT", 5*time.Second),
+		WriteTimeout:  getEnvDuration("REDIS_WRITE_TIMEOUT", 5*time.Second),
+		WeatherTTL:    getEnvDuration("REDIS_WEATHER_TTL", 3000*time.Second),
+		EventDedupTTL: getEnvDuration("REDIS_EVENT_DEDUP_TTL", 24*time.Hour),
 	}
 }
 
 type KafkaConfig struct {
-	Brokers []string
-	Topic   string
-	GroupID string
+	Brokers         []string
+	ClientID        string
+	Topic           string
+	StateTopic      string
+	StateBootstrap  bool
+	GroupID         string
+	EventMode       string
+	EventSource     string
+	CommitInterval  time.Duration
+	CommitBatchSize int
+
+	// LagCheckInterval is how often consumer lag is recomputed; MaxReadyLag
+	// is the total lag above which the service reports not ready (0 disables).
+	LagCheckInterval time.Duration
+	MaxReadyLag      int64
+
+	// TopicRoutes sends events of a provider to its own topic instead of
+	// Topic, given as KAFKA_TOPIC_ROUTES="weather=events.weather;...".
+	TopicRoutes map[string]string
+
+	DLQTopic          string
+	RetryTopics       []string
+	TopicPartitions   int
+	ReplicationFactor int
+	AutoCreateTopics  bool
+	AdminTimeout      time.Duration
+
+	SecurityProtocol string
+	SASLMechanism    string
+	SASLUsername     string
+	SASLPassword     string
+	TLSCAFile        string
+	TLSCertFile      string
+	TLSKeyFile       string
+	TLSKeyPassword   string
+	TLSSkipVerify    bool
+
+	// Overrides are raw librdkafka properties applied on top of everything
+	// else, given as KAFKA_OVERRIDES="key=value;key=value".
+	Overrides map[string]string
 }
 
 func NewKafkaConfig() *KafkaConfig {
 	return &KafkaConfig{
-		Brokers: []string{getEnv("KAFKA_BROKERS", "localhost:9091")},
-		Topic:   getEnv("KAFKA_TOPIC", "external.events.response"),
-		GroupID: getEnv("KAFKA_GROUP_ID", "aggregator"),
+		Brokers:         getEnvList("KAFKA_BROKERS", []string{"127.0.0.1:9091", "127.0.0.1:9092", "127.0.0.1:9093"}),
+		ClientID:        getEnv("KAFKA_CLIENT_ID", "aggregator"),
+		Topic:           getEnv("KAFKA_TOPIC", "external.events.response"),
+		StateTopic:      getEnv("KAFKA_STATE_TOPIC", "aggregator.state"),
+		StateBootstrap:  getEnvBool("KAFKA_STATE_BOOTSTRAP", true),
+		GroupID:         getEnv("KAFKA_GROUP_ID", "aggregator-consumer"),
+		EventMode:       getEnv("KAFKA_EVENT_MODE", "structured"),
+		EventSource:     getEnv("KAFKA_EVENT_SOURCE", "/service-info-aggregator"),
+		CommitInterval:  getEnvDuration("KAFKA_COMMIT_INTERVAL", 5*time.Second),
+		CommitBatchSize: getEnvInt("KAFKA_COMMIT_BATCH_SIZE", 100),
+
+		LagCheckInterval: getEnvDuration("KAFKA_LAG_CHECK_INTERVAL", 15*time.Second),
+		MaxReadyLag:      int64(getEnvInt("KAFKA_MAX_READY_LAG", 10000)),
+
+		TopicRoutes: getEnvMap("KAFKA_TOPIC_ROUTES"),
+
+		DLQTopic:          getEnv("KAFKA_DLQ_TOPIC", "external.events.response.dlq"),
+		RetryTopics:       getEnvList("KAFKA_RETRY_TOPICS", []string{"external.events.response.retry.1m", "external.events.response.retry.10m"}),
+		TopicPartitions:   getEnvInt("KAFKA_TOPIC_PARTITIONS", 3),
+		ReplicationFactor: getEnvInt("KAFKA_REPLICATION_FACTOR", 3),
+		AutoCreateTopics:  getEnvBool("KAFKA_AUTO_CREATE_TOPICS", true),
+		AdminTimeout:      getEnvDuration("KAFKA_ADMIN_TIMEOUT", 30*time.Second),
+
+		SecurityProtocol: getEnv("KAFKA_SECURITY_PROTOCOL", "PLAINTEXT"),
+		SASLMechanism:    getEnv("KAFKA_SASL_MECHANISM", ""),
+		SASLUsername:     getEnv("KAFKA_SASL_USERNAME", ""),
+		SASLPassword:     getEnv("KAFKA_SASL_PASSWORD", ""),
+		TLSCAFile:        getEnv("KAFKA_TLS_CA_FILE", ""),
+		TLSCertFile:      getEnv("KAFKA_TLS_CERT_FILE", ""),
+		TLSKeyFile:       getEnv("KAFKA_TLS_KEY_FILE", ""),
+		TLSKeyPassword:   getEnv("KAFKA_TLS_KEY_PASSWORD", ""),
+		TLSSkipVerify:    getEnvBool("KAFKA_TLS_SKIP_VERIFY", false),
+
+		Overrides: getEnvMap("KAFKA_OVERRIDES"),
+	}
+}
+
+type MessagingConfig struct {
+	Transport           string
+	HandlerTimeout      time.Duration
+	MemoryPartitions    int
+	StreamMaxLen        int
+	StreamConsumer      string
+	StreamBatchSize     int
+	StreamBlockTimeout  time.Duration
+	StreamClaimMinIdle  time.Duration
+	StreamClaimInterval time.Duration
+	// StreamMaxDeliveries moves an entry to the dead-letter stream, its
+	// stream name plus StreamDeadLetterSuffix, once it was delivered that
+	// many times without being acknowledged.
+	StreamMaxDeliveries    int
+	StreamDeadLetterSuffix string
+
+	SerializationFormat    string
+	SchemaRegistryURL      string
+	SchemaRegistryUsername string
+	SchemaRegistryPassword string
+	SchemaRegistryTimeout  time.Duration
+}
+
+func NewMessagingConfig() *MessagingConfig {
+	hostname, _ := os.Hostname()
+
+	return &MessagingConfig{
+		Transport:           getEnv("MESSAGING_TRANSPORT", "kafka"),
+		HandlerTimeout:      getEnvDuration("MESSAGING_HANDLER_TIMEOUT", 10*time.Second),
+		MemoryPartitions:    getEnvInt("MESSAGING_MEMORY_PARTITIONS", 3),
+		StreamMaxLen:        getEnvInt("MESSAGING_STREAM_MAX_LEN", 100000),
+		StreamConsumer:      getEnv("MESSAGING_STREAM_CONSUMER", hostname),
+		StreamBatchSize:     getEnvInt("MESSAGING_STREAM_BATCH_SIZE", 100),
+		StreamBlockTimeout:  getEnvDuration("MESSAGING_STREAM_BLOCK_TIMEOUT", time.Second),
+		StreamClaimMinIdle:  getEnvDuration("MESSAGING_STREAM_CLAIM_MIN_IDLE", time.Minute),
+		StreamClaimInterval: getEnvDuration("MESSAGING_STREAM_CLAIM_INTERVAL", 30*time.Second),
+
+		StreamMaxDeliveries:    getEnvInt("MESSAGING_STREAM_MAX_DELIVERIES", 5),
+		StreamDeadLetterSuffix: getEnv("MESSAGING_STREAM_DEAD_LETTER_SUFFIX", ".dlq"),
+
+		SerializationFormat:    getEnv("MESSAGING_SERIALIZATION_FORMAT", "json"),
+		SchemaRegistryURL:      getEnv("SCHEMA_REGISTRY_URL", ""),
+		SchemaRegistryUsername: getEnv("SCHEMA_REGISTRY_USERNAME", ""),
+		SchemaRegistryPassword: getEnv("SCHEMA_REGISTRY_PASSWORD", ""),
+		SchemaRegistryTimeout:  getEnvDuration("SCHEMA_REGISTRY_TIMEOUT", 5*time.Second),
+	}
+}
+
+type SchedulerConfig struct {
+	// Listen applies popular_data changes as Postgres notifies them, which
+	// leaves the periodic reload as a safety net only.
+	Listen                 bool
+	ReloadInterval         time.Duration
+	DefaultRefreshInterval time.Duration
+	Workers                int
+	FetchTimeout           time.Duration
+	// ProviderConcurrency caps the refreshes running at once per provider,
+	// given as SCHEDULER_PROVIDER_CONCURRENCY="weather=4;news=2".
+	ProviderConcurrency map[string]int
+	HistorySize         int
+	PauseKey            string
+	TriggerKey          string
+	// AdminToken authorizes pausing, resuming and triggering the scheduler;
+	// those endpoints are disabled without it.
+	AdminToken string
+
+	// Coordination is "leader" to run the scheduler on one replica at a
+	// time, "sharded" to split the items between live replicas, or "none"
+	// to run every item everywhere.
+	Coordination string
+	InstanceID   string
+	LeaderKey    string
+	LeaderLease  time.Duration
+
+	MembershipKey     string
+	HeartbeatInterval time.Duration
+	MemberTTL         time.Duration
+	RingReplicas      int
+}
+
+func NewSchedulerConfig() *SchedulerConfig {
+	hostname, _ := os.Hostname()
+
+	listen := getEnvBool("SCHEDULER_LISTEN", true)
+	reloadInterval := 30 * time.Second
+	if listen {
+		reloadInterval = 5 * time.Minute
+	}
+
+	return &SchedulerConfig{
+		Listen:                 listen,
+		ReloadInterval:         getEnvDuration("SCHEDULER_RELOAD_INTERVAL", reloadInterval),
+		DefaultRefreshInterval: getEnvDuration("SCHEDULER_DEFAULT_REFRESH_INTERVAL", 30*time.Second),
+		Workers:                getEnvInt("SCHEDULER_WORKERS", 10),
+		FetchTimeout:           getEnvDuration("SCHEDULER_FETCH_TIMEOUT", 10*time.Second),
+		ProviderConcurrency:    getEnvIntMap("SCHEDULER_PROVIDER_CONCURRENCY"),
+		HistorySize:            getEnvInt("SCHEDULER_HISTORY_SIZE", 200),
+		PauseKey:               getEnv("SCHEDULER_PAUSE_KEY", "scheduler:paused"),
+		TriggerKey:             getEnv("SCHEDULER_TRIGGER_KEY", "scheduler:triggers"),
+		AdminToken:             getEnv("ADMIN_TOKEN", ""),
+
+		Coordination: getEnv("SCHEDULER_COORDINATION", "leader"),
+		InstanceID:   getEnv("SCHEDULER_INSTANCE_ID", fmt.Sprintf("%s-%d", hostname, os.Getpid())),
+		LeaderKey:    getEnv("SCHEDULER_LEADER_KEY", "scheduler:leader"),
+		LeaderLease:  getEnvDuration("SCHEDULER_LEADER_LEASE", 10*time.Second),
+
+		MembershipKey:     getEnv("SCHEDULER_MEMBERSHIP_KEY", "scheduler:members"),
+		HeartbeatInterval: getEnvDuration("SCHEDULER_HEARTBEAT_INTERVAL", 3*time.Second),
+		MemberTTL:         getEnvDuration("SCHEDULER_MEMBER_TTL", 10*time.Second),
+		RingReplicas:      getEnvInt("SCHEDULER_RING_REPLICAS", 64),
+	}
+}
+
+// JobQueueConfig configures the persistent refresh job queue. When enabled
+// the scheduler only enqueues due items and job workers on every replica
+// run them.
+type JobQueueConfig struct {
+	Enabled           bool
+	Workers           int
+	PollInterval      time.Duration
+	VisibilityTimeout time.Duration
+	FetchTimeout      time.Duration
+	MaxAttempts       int
+	BackoffBase       time.Duration
+	BackoffMax        time.Duration
+	// ProviderConcurrency is read from SCHEDULER_PROVIDER_CONCURRENCY, so the
+	// limits hold whether the scheduler or a job worker runs the refresh.
+	ProviderConcurrency map[string]int
+}
+
+func NewJobQueueConfig() *JobQueueConfig {
+	return &JobQueueConfig{
+		Enabled:           getEnvBool("JOB_QUEUE_ENABLED", true),
+		Workers:           getEnvInt("JOB_QUEUE_WORKERS", 10),
+		PollInterval:      getEnvDuration("JOB_QUEUE_POLL_INTERVAL", time.Second),
+		VisibilityTimeout: getEnvDuration("JOB_QUEUE_VISIBILITY_TIMEOUT", time.Minute),
+		FetchTimeout:      getEnvDuration("JOB_QUEUE_FETCH_TIMEOUT", 10*time.Second),
+		MaxAttempts:       getEnvInt("JOB_QUEUE_MAX_ATTEMPTS", 5),
+		BackoffBase:       getEnvDuration("JOB_QUEUE_BACKOFF_BASE", 5*time.Second),
+		BackoffMax:        getEnvDuration("JOB_QUEUE_BACKOFF_MAX", 5*time.Minute),
+
+		ProviderConcurrency: getEnvIntMap("SCHEDULER_PROVIDER_CONCURRENCY"),
+	}
+}
+
+// WarmupConfig configures filling the cache with every popular key at
+// startup, before the instance reports ready and the scheduler starts, and
+// for saved items.
+type WarmupConfig struct {
+	OnStartup    bool
+	Rate         int
+	FetchTimeout time.Duration
+	MaxDuration  time.Duration
+	QueueSize    int
+}
+
+func NewWarmupConfig() *WarmupConfig {
+	return &WarmupConfig{
+		OnStartup:    getEnvBool("WARMUP_ON_STARTUP", true),
+		Rate:         getEnvInt("WARMUP_RATE", 20),
+		FetchTimeout: getEnvDuration("WARMUP_FETCH_TIMEOUT", 10*time.Second),
+		MaxDuration:  getEnvDuration("WARMUP_MAX_DURATION", 2*time.Minute),
+		QueueSize:    getEnvInt("WARMUP_QUEUE_SIZE", 100),
+	}
+}
+
+type PopularityConfig struct {
+	BucketDuration time.Duration
+	Buckets        int
+	// Decay is the weight of a bucket relative to the next newer one.
+	Decay            float64
+	PromoteThreshold float64
+	DemoteThreshold  float64
+	PromoteInterval  time.Duration
+	MaxPromoted      int
+}
+
+func NewPopularityConfig() *PopularityConfig {
+	return &PopularityConfig{
+		BucketDuration:   getEnvDuration("POPULARITY_BUCKET_DURATION", 10*time.Minute),
+		Buckets:          getEnvInt("POPULARITY_BUCKETS", 36),
+		Decay:            getEnvFloat("POPULARITY_DECAY", 0.8),
+		PromoteThreshold: getEnvFloat("POPULARITY_PROMOTE_THRESHOLD", 50),
+		DemoteThreshold:  getEnvFloat("POPULARITY_DEMOTE_THRESHOLD", 5),
+		PromoteInterval:  getEnvDuration("POPULARITY_PROMOTE_INTERVAL", time.Minute),
+		MaxPromoted:      getEnvInt("POPULARITY_MAX_PROMOTED", 100),
 	}
 }
 
@@ -88,6 +349,60 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value, ok := os.LookupEnv(key); ok {
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return v
+		}
+	}
+	return defaultValue
+}
+
+func getEnvList(key string, defaultValue []string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return defaultValue
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func getEnvMap(key string) map[string]string {
+	m := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv(key), ";") {
+		k, v, ok := strings.Cut(pair, "=")
+		if k = strings.TrimSpace(k); ok && k != "" {
+			m[k] = strings.TrimSpace(v)
+		}
+	}
+	return m
+}
+
+func getEnvIntMap(key string) map[string]int {
+	m := make(map[string]int)
+	for k, v := range getEnvMap(key) {
+		if n, err := strconv.Atoi(v); err == nil {
+			m[k] = n
+		}
+	}
+	return m
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value, ok := os.LookupEnv(key); ok {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
+
 func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	if value, ok := os.LookupEnv(key); ok {
 		if d, err := time.ParseDuration(value); err == nil {
@@ -0,0 +1,207 @@
+package coordination
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// acquireScript takes the lock when it is free and stores a fresh fencing
+// token with it. KEYS: lock key, token counter key. ARGV: instance ID, lease
+// in milliseconds. Returns the token, or 0 when the lock is held.
+var acquireScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return 0
+end
+local token = redis.call('INCR', KEYS[2])
+redis.call('SET', KEYS[1], token .. ':' .. ARGV[1], 'PX', ARGV[2])
+return token
+`)
+
+// renewScript extends the lease only while the lock still holds our value.
+var renewScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+type LeaderStatus struct {
+	Leader    string        `json:"leader"`
+	Token     int64         `json:"token"`
+	ExpiresIn time.Duration `json:"expires_in"`
+	Self      bool          `json:"self"`
+}
+
+// Elector runs a Redis lease lock. The holder renews it every third of the
+// lease and steps down when it cannot renew for half a lease, well before
+// another instance may take over. Every new leadership gets a higher fencing
+// token.
+type Elector struct {
+	client     *redis.Client
+	key        string
+	instanceID string
+	lease      time.Duration
+
+	mu    sync.RWMutex
+	token int64
+}
+
+func NewElector(client *redis.Client, key, instanceID string, lease time.Duration) *Elector {
+	return &Elector{
+		client:     client,
+		key:        key,
+		instanceID: instanceID,
+		lease:      lease,
+	}
+}
+
+// Run campaigns until ctx is cancelled. While this instance is the leader,
+// lead runs with a context that is cancelled as soon as leadership is lost.
+func (e *Elector) Run(ctx context.Context, lead func(ctx context.Context, token int64)) {
+	interval := e.lease / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		token, err := e.acquire(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Warn("leader election failed", "key", e.key, "error", err)
+		}
+		if token > 0 {
+			e.hold(ctx, token, lead)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Elector) hold(ctx context.Context, token int64, lead func(ctx context.Context, token int64)) {
+	slog.Info("elected leader", "key", e.key, "instance", e.instanceID, "token", token)
+	e.setToken(token)
+	defer e.setToken(0)
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lead(leaderCtx, token)
+	}()
+
+	e.renewLoop(leaderCtx, token, done)
+	cancel()
+	<-done
+
+	// Free the lock right away on shutdown so another replica takes over
+	// without waiting for the lease to expire.
+	releaseCtx, cancelRelease := context.WithTimeout(context.Background(), time.Second)
+	defer cancelRelease()
+	if err := releaseScript.Run(releaseCtx, e.client, []string{e.key}, e.value(token)).Err(); err != nil {
+		slog.Warn("failed to release leader lock", "key", e.key, "error", err)
+	}
+	slog.Info("leadership ended", "key", e.key, "instance", e.instanceID, "token", token)
+}
+
+func (e *Elector) renewLoop(ctx context.Context, token int64, done <-chan struct{}) {
+	ticker := time.NewTicker(e.lease / 3)
+	defer ticker.Stop()
+
+	renewed := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		started := time.Now()
+		ok, err := renewScript.Run(ctx, e.client, []string{e.key}, e.value(token), e.lease.Milliseconds()).Bool()
+		switch {
+		case err != nil && !errors.Is(err, redis.Nil):
+			if time.Since(renewed) > e.lease/2 {
+				slog.Error("could not renew leader lease, stepping down", "key", e.key, "error", err)
+				return
+			}
+			slog.Warn("failed to renew leader lease", "key", e.key, "error", err)
+		case !ok:
+			slog.Warn("leader lock lost", "key", e.key, "token", token)
+			return
+		default:
+			renewed = started
+		}
+	}
+}
+
+// TokenKey names the counter holding the latest fencing token of the lock
+// key.
+func TokenKey(key string) string {
+	return key + ":token"
+}
+
+func (e *Elector) acquire(ctx context.Context) (int64, error) {
+	return acquireScript.Run(ctx, e.client, []string{e.key, TokenKey(e.key)},
+		e.instanceID, e.lease.Milliseconds()).Int64()
+}
+
+func (e *Elector) value(token int64) string {
+	return strconv.FormatInt(token, 10) + ":" + e.instanceID
+}
+
+func (e *Elector) setToken(token int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.token = token
+}
+
+// Token returns the fencing token of the current leadership, or 0 when this
+// instance is not the leader.
+func (e *Elector) Token() int64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.token
+}
+
+// Status reports the instance currently holding the lock, as seen in Redis.
+func (e *Elector) Status(ctx context.Context) (*LeaderStatus, error) {
+	pipe := e.client.Pipeline()
+	get := pipe.Get(ctx, e.key)
+	ttl := pipe.PTTL(ctx, e.key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	rawToken, leader, _ := strings.Cut(get.Val(), ":")
+	token, err := strconv.ParseInt(rawToken, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LeaderStatus{
+		Leader:    leader,
+		Token:     token,
+		ExpiresIn: ttl.Val(),
+		Self:      leader == e.instanceID,
+	}, nil
+}
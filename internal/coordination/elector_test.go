@@ -0,0 +1,100 @@
+package coordination_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"service-info-aggregator/internal/coordination"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return server, client
+}
+
+type leadership struct {
+	mu     sync.Mutex
+	tokens []int64
+	active string
+}
+
+func (l *leadership) lead(instance string) func(ctx context.Context, token int64) {
+	return func(ctx context.Context, token int64) {
+		l.mu.Lock()
+		l.tokens = append(l.tokens, token)
+		l.active = instance
+		l.mu.Unlock()
+
+		<-ctx.Done()
+
+		l.mu.Lock()
+		l.active = ""
+		l.mu.Unlock()
+	}
+}
+
+func (l *leadership) current() (string, []int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.active, append([]int64(nil), l.tokens...)
+}
+
+func TestElector_FailoverIncrementsToken(t *testing.T) {
+	_, client := newTestRedis(t)
+	var state leadership
+
+	first := coordination.NewElector(client, "scheduler:leader", "a", 300*time.Millisecond)
+	second := coordination.NewElector(client, "scheduler:leader", "b", 300*time.Millisecond)
+
+	ctxA, cancelA := context.WithCancel(context.Background())
+	doneA := make(chan struct{})
+	go func() {
+		defer close(doneA)
+		first.Run(ctxA, state.lead("a"))
+	}()
+
+	require.Eventually(t, func() bool { active, _ := state.current(); return active == "a" }, time.Second, 5*time.Millisecond)
+
+	ctxB, cancelB := context.WithCancel(context.Background())
+	defer cancelB()
+	go second.Run(ctxB, state.lead("b"))
+
+	status, err := second.Status(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "a", status.Leader)
+	assert.False(t, status.Self)
+	assert.Zero(t, second.Token())
+
+	cancelA()
+	<-doneA
+
+	require.Eventually(t, func() bool { active, _ := state.current(); return active == "b" }, time.Second, 5*time.Millisecond)
+	_, tokens := state.current()
+	assert.Equal(t, []int64{1, 2}, tokens)
+	assert.Equal(t, int64(2), second.Token())
+}
+
+func TestElector_StepsDownWhenLockIsLost(t *testing.T) {
+	server, client := newTestRedis(t)
+	var state leadership
+
+	elector := coordination.NewElector(client, "scheduler:leader", "a", 300*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go elector.Run(ctx, state.lead("a"))
+
+	require.Eventually(t, func() bool { active, _ := state.current(); return active == "a" }, time.Second, 5*time.Millisecond)
+
+	server.Set("scheduler:leader", "99:intruder")
+
+	require.Eventually(t, func() bool { active, _ := state.current(); return active == "" }, time.Second, 5*time.Millisecond)
+}
@@ -0,0 +1,157 @@
+package coordination
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type MembershipStatus struct {
+	Self    string   `json:"self"`
+	Members []string `json:"members"`
+}
+
+// Membership keeps the list of live replicas in a Redis sorted set scored by
+// heartbeat expiry and builds a hash ring over it. Members that miss their
+// heartbeats for the TTL drop out of everyone's ring on the next beat.
+type Membership struct {
+	client     *redis.Client
+	key        string
+	instanceID string
+	interval   time.Duration
+	ttl        time.Duration
+	replicas   int
+	onChange   func()
+
+	mu      sync.RWMutex
+	members []string
+	ring    *Ring
+	// beat is when the last successful heartbeat started. Once it is older
+	// than the TTL, the other replicas have dropped this one from their rings.
+	beat time.Time
+	// lapsed is set while this instance owns nothing because its heartbeats
+	// kept failing for the TTL.
+	lapsed bool
+}
+
+func NewMembership(client *redis.Client, key, instanceID string, interval, ttl time.Duration, replicas int) *Membership {
+	return &Membership{
+		client:     client,
+		key:        key,
+		instanceID: instanceID,
+		interval:   interval,
+		ttl:        ttl,
+		replicas:   replicas,
+		ring:       NewRing(nil, replicas),
+	}
+}
+
+// OnChange registers a callback run after the member list changes.
+func (m *Membership) OnChange(fn func()) {
+	m.onChange = fn
+}
+
+// Run heartbeats until ctx is cancelled, then leaves the ring so the other
+// replicas pick up this instance's keys without waiting for the TTL.
+func (m *Membership) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.heartbeat(ctx); err != nil && ctx.Err() == nil {
+			slog.Warn("membership heartbeat failed", "key", m.key, "error", err)
+			m.checkLapse()
+		}
+
+		select {
+		case <-ctx.Done():
+			leaveCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			if err := m.client.ZRem(leaveCtx, m.key, m.instanceID).Err(); err != nil {
+				slog.Warn("failed to leave membership", "key", m.key, "error", err)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Membership) heartbeat(ctx context.Context) error {
+	// A hanging call must not delay noticing that the membership expired.
+	ctx, cancel := context.WithTimeout(ctx, m.interval)
+	defer cancel()
+
+	now := time.Now()
+
+	pipe := m.client.TxPipeline()
+	pipe.ZAdd(ctx, m.key, redis.Z{Score: float64(now.Add(m.ttl).UnixMilli()), Member: m.instanceID})
+	pipe.ZRemRangeByScore(ctx, m.key, "-inf", strconv.FormatInt(now.UnixMilli(), 10))
+	members := pipe.ZRange(ctx, m.key, 0, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	live := members.Val()
+	slices.Sort(live)
+
+	m.mu.Lock()
+	m.beat = now
+	changed := !slices.Equal(live, m.members) || m.lapsed
+	m.lapsed = false
+	if changed {
+		m.members = live
+		m.ring = NewRing(live, m.replicas)
+	}
+	m.mu.Unlock()
+
+	if changed {
+		slog.Info("scheduler membership changed", "members", live)
+		if m.onChange != nil {
+			m.onChange()
+		}
+	}
+	return nil
+}
+
+// checkLapse tells the listener once the membership expired, so that it
+// stops working on keys the other replicas have taken over.
+func (m *Membership) checkLapse() {
+	m.mu.Lock()
+	beat := m.beat
+	lapsed := !m.lapsed && !beat.IsZero() && time.Since(beat) > m.ttl
+	if lapsed {
+		m.lapsed = true
+	}
+	m.mu.Unlock()
+
+	if lapsed {
+		slog.Warn("scheduler membership expired, owning no keys", "key", m.key, "last_heartbeat", beat)
+		if m.onChange != nil {
+			m.onChange()
+		}
+	}
+}
+
+// Owns reports whether key hashes to this instance. Before the first
+// heartbeat the ring is empty and nothing is owned; when heartbeats keep
+// failing for the TTL, nothing is owned either, since another replica takes
+// the keys over.
+func (m *Membership) Owns(key string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if time.Since(m.beat) > m.ttl {
+		return false
+	}
+	return m.ring.Owner(key) == m.instanceID
+}
+
+func (m *Membership) Status() MembershipStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return MembershipStatus{Self: m.instanceID, Members: slices.Clone(m.members)}
+}
@@ -0,0 +1,90 @@
+package coordination_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"service-info-aggregator/internal/coordination"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRing_MovesFewKeysOnJoin(t *testing.T) {
+	before := coordination.NewRing([]string{"a", "b", "c"}, 64)
+	after := coordination.NewRing([]string{"a", "b", "c", "d"}, 64)
+
+	owned := map[string]int{}
+	moved := 0
+	for i := range 1000 {
+		key := fmt.Sprintf("weather:city-%d", i)
+		owner := after.Owner(key)
+		owned[owner]++
+		if before.Owner(key) != owner {
+			assert.Equal(t, "d", owner, key)
+			moved++
+		}
+	}
+
+	assert.Len(t, owned, 4)
+	assert.Less(t, moved, 450)
+	assert.Empty(t, coordination.NewRing(nil, 64).Owner("weather:Moscow"))
+}
+
+func TestMembership_SplitsKeysBetweenLiveMembers(t *testing.T) {
+	_, client := newTestRedis(t)
+
+	a := coordination.NewMembership(client, "scheduler:members", "a", 20*time.Millisecond, time.Second, 64)
+	b := coordination.NewMembership(client, "scheduler:members", "b", 20*time.Millisecond, time.Second, 64)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go a.Run(ctx)
+
+	ctxB, cancelB := context.WithCancel(context.Background())
+	doneB := make(chan struct{})
+	go func() {
+		defer close(doneB)
+		b.Run(ctxB)
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(a.Status().Members) == 2 && len(b.Status().Members) == 2
+	}, time.Second, 5*time.Millisecond)
+
+	for i := range 100 {
+		key := fmt.Sprintf("weather:city-%d", i)
+		assert.NotEqual(t, a.Owns(key), b.Owns(key), key)
+	}
+
+	cancelB()
+	<-doneB
+
+	require.Eventually(t, func() bool { return len(a.Status().Members) == 1 }, time.Second, 5*time.Millisecond)
+	assert.True(t, a.Owns("weather:Moscow"))
+}
+
+func TestMembership_OwnsNothingAfterHeartbeatsFail(t *testing.T) {
+	server, client := newTestRedis(t)
+
+	m := coordination.NewMembership(client, "scheduler:members", "a", 20*time.Millisecond, 200*time.Millisecond, 64)
+	var changes atomic.Int32
+	m.OnChange(func() { changes.Add(1) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx)
+
+	require.Eventually(t, func() bool { return m.Owns("weather:Moscow") }, time.Second, 5*time.Millisecond)
+
+	server.Close()
+
+	// The ring still lists this instance, but its membership has expired.
+	require.Eventually(t, func() bool { return !m.Owns("weather:Moscow") }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"a"}, m.Status().Members)
+	// Joining and the lapse itself.
+	require.Eventually(t, func() bool { return changes.Load() == 2 }, time.Second, 5*time.Millisecond)
+}
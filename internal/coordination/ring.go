@@ -0,0 +1,50 @@
+package coordination
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// Ring is a consistent hash ring: every member owns the keys hashed between
+// its virtual nodes and the previous ones, so a join or leave only moves
+// about 1/N of the keys.
+type Ring struct {
+	hashes []uint64
+	owners map[uint64]string
+}
+
+func NewRing(members []string, replicas int) *Ring {
+	r := &Ring{owners: make(map[uint64]string, len(members)*replicas)}
+	for _, m := range members {
+		for i := range replicas {
+			h := hashKey(m + "#" + strconv.Itoa(i))
+			if _, taken := r.owners[h]; taken {
+				continue
+			}
+			r.owners[h] = m
+			r.hashes = append(r.hashes, h)
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+	return r
+}
+
+// Owner returns the member responsible for key, or "" for an empty ring.
+func (r *Ring) Owner(key string) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+	h := hashKey(key)
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.owners[r.hashes[i]]
+}
+
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
@@ -0,0 +1,265 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"service-info-aggregator/internal/background"
+	"service-info-aggregator/internal/coordination"
+	"service-info-aggregator/internal/messaging"
+	"service-info-aggregator/internal/messaging/kafka"
+)
+
+type AdminHandler struct {
+	throughput *messaging.ThroughputTracker
+	lagMonitor *kafka.LagMonitor
+	elector    *coordination.Elector
+	membership *coordination.Membership
+	scheduler  *background.PriorityScheduler
+	token      string
+}
+
+type Option func(*AdminHandler)
+
+// WithLagMonitor adds Kafka consumer lag to the consumer endpoint.
+func WithLagMonitor(m *kafka.LagMonitor) Option {
+	return func(h *AdminHandler) {
+		h.lagMonitor = m
+	}
+}
+
+// WithElector reports the scheduler leader on the scheduler endpoint.
+func WithElector(e *coordination.Elector) Option {
+	return func(h *AdminHandler) {
+		h.elector = e
+	}
+}
+
+// WithMembership reports the replicas sharing the schedule.
+func WithMembership(m *coordination.Membership) Option {
+	return func(h *AdminHandler) {
+		h.membership = m
+	}
+}
+
+// WithScheduler enables pausing, triggering and inspecting the scheduler.
+func WithScheduler(s *background.PriorityScheduler) Option {
+	return func(h *AdminHandler) {
+		h.scheduler = s
+	}
+}
+
+// WithToken enables pausing, resuming and triggering the scheduler for
+// requests carrying "Authorization: Bearer <token>". Without a token these
+// endpoints are disabled.
+func WithToken(token string) Option {
+	return func(h *AdminHandler) {
+		h.token = token
+	}
+}
+
+func NewAdminHandler(throughput *messaging.ThroughputTracker, opts ...Option) *AdminHandler {
+	h := &AdminHandler{throughput: throughput}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func (h *AdminHandler) HandleConsumer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		responseWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	response := map[string]any{
+		"event_types": h.throughput.Snapshot(),
+	}
+	if h.lagMonitor != nil {
+		response["lag"] = h.lagMonitor.Snapshot()
+	}
+
+	responseWithJSON(w, http.StatusOK, response)
+}
+
+func (h *AdminHandler) HandleScheduler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		responseWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	response := map[string]any{}
+	if h.elector != nil {
+		leader, err := h.elector.Status(r.Context())
+		if err != nil {
+			responseWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		response["leader"] = leader
+	}
+	if h.membership != nil {
+		response["membership"] = h.membership.Status()
+	}
+	if h.scheduler != nil {
+		paused, err := h.scheduler.Paused(r.Context())
+		if err != nil {
+			responseWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		response["running"] = h.scheduler.Running()
+		response["paused"] = paused
+	}
+
+	responseWithJSON(w, http.StatusOK, response)
+}
+
+// HandlePause pauses the scheduler on every replica, or only the data type
+// given by ?type=.
+func (h *AdminHandler) HandlePause(w http.ResponseWriter, r *http.Request) {
+	if !h.controlRequest(w, r) {
+		return
+	}
+
+	if err := h.scheduler.Pause(r.Context(), r.URL.Query().Get("type")); err != nil {
+		responseWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.respondWithPauses(w, r)
+}
+
+// HandleResume resumes the data type given by ?type=, or everything.
+func (h *AdminHandler) HandleResume(w http.ResponseWriter, r *http.Request) {
+	if !h.controlRequest(w, r) {
+		return
+	}
+
+	if err := h.scheduler.Resume(r.Context(), r.URL.Query().Get("type")); err != nil {
+		responseWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.respondWithPauses(w, r)
+}
+
+func (h *AdminHandler) respondWithPauses(w http.ResponseWriter, r *http.Request) {
+	paused, err := h.scheduler.Paused(r.Context())
+	if err != nil {
+		responseWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	responseWithJSON(w, http.StatusOK, paused)
+}
+
+// HandleTrigger refreshes the item given by ?id= now, or every item when no
+// id is given.
+func (h *AdminHandler) HandleTrigger(w http.ResponseWriter, r *http.Request) {
+	if !h.controlRequest(w, r) {
+		return
+	}
+
+	idParam := r.URL.Query().Get("id")
+	if idParam == "" {
+		n, err := h.scheduler.TriggerAll(r.Context())
+		if err != nil {
+			responseWithTriggerError(w, err)
+			return
+		}
+		responseWithJSON(w, http.StatusAccepted, map[string]int{"triggered": n})
+		return
+	}
+
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		responseWithError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.scheduler.Trigger(r.Context(), id); err != nil {
+		responseWithTriggerError(w, err)
+		return
+	}
+	responseWithJSON(w, http.StatusAccepted, map[string]int{"triggered": 1})
+}
+
+// HandleRuns lists the most recent runs, newest first, optionally of one
+// data type only.
+func (h *AdminHandler) HandleRuns(w http.ResponseWriter, r *http.Request) {
+	if !h.schedulerRequest(w, r, http.MethodGet) {
+		return
+	}
+
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 {
+			responseWithError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	runs := h.scheduler.Runs(h.scheduler.HistorySize())
+	if dataType := r.URL.Query().Get("type"); dataType != "" {
+		filtered := make([]background.RunRecord, 0, len(runs))
+		for _, run := range runs {
+			if run.DataType == dataType {
+				filtered = append(filtered, run)
+			}
+		}
+		runs = filtered
+	}
+	runs = runs[:min(limit, len(runs))]
+
+	responseWithJSON(w, http.StatusOK, map[string]any{"runs": runs})
+}
+
+func (h *AdminHandler) schedulerRequest(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		responseWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return false
+	}
+	if h.scheduler == nil {
+		responseWithError(w, http.StatusNotFound, "scheduler is not configured")
+		return false
+	}
+	return true
+}
+
+// controlRequest admits requests changing the scheduler.
+func (h *AdminHandler) controlRequest(w http.ResponseWriter, r *http.Request) bool {
+	if !h.schedulerRequest(w, r, http.MethodPost) {
+		return false
+	}
+	if h.token == "" {
+		responseWithError(w, http.StatusForbidden, "admin token is not configured")
+		return false
+	}
+	expected := []byte("Bearer " + h.token)
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+		responseWithError(w, http.StatusUnauthorized, "invalid admin token")
+		return false
+	}
+	return true
+}
+
+func responseWithTriggerError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, background.ErrItemNotFound):
+		responseWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, background.ErrNotRunning):
+		responseWithError(w, http.StatusConflict, err.Error())
+	default:
+		responseWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func responseWithJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(payload)
+}
+
+func responseWithError(w http.ResponseWriter, status int, message string) {
+	responseWithJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,226 @@
+package admin_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"service-info-aggregator/internal/background"
+	"service-info-aggregator/internal/config"
+	"service-info-aggregator/internal/handler/admin"
+	"service-info-aggregator/internal/messaging"
+	"service-info-aggregator/internal/messaging/memory"
+	"service-info-aggregator/internal/model/dto"
+	popularDataRepo "service-info-aggregator/internal/repository/popular_data"
+	"service-info-aggregator/internal/repository/scheduler_pauses"
+	"service-info-aggregator/internal/repository/scheduler_triggers"
+	"service-info-aggregator/internal/service/aggregation"
+	"service-info-aggregator/internal/service/popular_data"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type emptyRepository struct{}
+
+func (r emptyRepository) Create(ctx context.Context, d *dto.PopularDataDto) (*dto.PopularDataDto, error) {
+	return d, nil
+}
+
+func (r emptyRepository) GetAll(ctx context.Context) ([]dto.PopularDataDto, error) {
+	return nil, nil
+}
+
+func (r emptyRepository) GetById(ctx context.Context, id int) (*dto.PopularDataDto, error) {
+	return nil, nil
+}
+
+func (r emptyRepository) Update(ctx context.Context, id int, d *dto.PopularDataDto) (*dto.PopularDataDto, error) {
+	return d, nil
+}
+
+func (r emptyRepository) Delete(ctx context.Context, id int) error {
+	return nil
+}
+
+const token = "secret"
+
+type itemRepository struct {
+	emptyRepository
+	item dto.PopularDataDto
+}
+
+func (r itemRepository) GetAll(ctx context.Context) ([]dto.PopularDataDto, error) {
+	return []dto.PopularDataDto{r.item}, nil
+}
+
+func (r itemRepository) GetById(ctx context.Context, id int) (*dto.PopularDataDto, error) {
+	if id != r.item.ID {
+		return nil, nil
+	}
+	return &r.item, nil
+}
+
+func newTestScheduler(store background.PauseStore) *background.PriorityScheduler {
+	return newSchedulerWith(emptyRepository{}, store)
+}
+
+func newSchedulerWith(repo popularDataRepo.Repository, store background.PauseStore) *background.PriorityScheduler {
+	aggService := aggregation.NewAggregationService(memory.NewBroker(1), messaging.NewJSONSerializer(messaging.EventModeStructured),
+		messaging.NewTopicRoutes("events", nil), "", "/test")
+	scheduler := background.NewPriorityScheduler(popular_data.NewPopularDataService(repo), aggService,
+		&config.SchedulerConfig{
+			ReloadInterval:         time.Hour,
+			DefaultRefreshInterval: time.Hour,
+			Workers:                1,
+			FetchTimeout:           time.Second,
+			HistorySize:            10,
+		}, &aggregation.WeatherProvider{})
+	if store != nil {
+		scheduler.SetPauseStore(store)
+	}
+	return scheduler
+}
+
+func serve(handler http.HandlerFunc, method, target string) *httptest.ResponseRecorder {
+	return serveWithToken(handler, method, target, token)
+}
+
+func serveWithToken(handler http.HandlerFunc, method, target, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func TestAdminHandler_PauseIsSharedBetweenReplicas(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	// The pause reaches a replica that is not running the scheduler.
+	follower := admin.NewAdminHandler(messaging.NewThroughputTracker(), admin.WithToken(token),
+		admin.WithScheduler(newTestScheduler(scheduler_pauses.NewRedisRepository(client, "scheduler:paused"))))
+	leader := admin.NewAdminHandler(messaging.NewThroughputTracker(), admin.WithToken(token),
+		admin.WithScheduler(newTestScheduler(scheduler_pauses.NewRedisRepository(client, "scheduler:paused"))))
+
+	rec := serve(follower.HandlePause, http.MethodPost, "/admin/scheduler/pause?type=weather")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"all":false,"types":["weather"]}`, rec.Body.String())
+
+	rec = serve(leader.HandleScheduler, http.MethodGet, "/admin/scheduler")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var status struct {
+		Running bool                  `json:"running"`
+		Paused  background.PauseState `json:"paused"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.False(t, status.Running)
+	assert.Equal(t, []string{"weather"}, status.Paused.Types)
+
+	rec = serve(leader.HandleResume, http.MethodPost, "/admin/scheduler/resume")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"all":false,"types":[]}`, rec.Body.String())
+
+	rec = serve(follower.HandlePause, http.MethodGet, "/admin/scheduler/pause")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestAdminHandler_RequiresTokenToControlScheduler(t *testing.T) {
+	h := admin.NewAdminHandler(messaging.NewThroughputTracker(), admin.WithToken(token), admin.WithScheduler(newTestScheduler(nil)))
+
+	for _, handler := range []http.HandlerFunc{h.HandlePause, h.HandleResume, h.HandleTrigger} {
+		rec := serveWithToken(handler, http.MethodPost, "/admin/scheduler", "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = serveWithToken(handler, http.MethodPost, "/admin/scheduler", "wrong")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+
+	rec := serveWithToken(h.HandleRuns, http.MethodGet, "/admin/scheduler/runs", "")
+	assert.Equal(t, http.StatusOK, rec.Code, "reading needs no token")
+
+	disabled := admin.NewAdminHandler(messaging.NewThroughputTracker(), admin.WithScheduler(newTestScheduler(nil)))
+	rec = serve(disabled.HandlePause, http.MethodPost, "/admin/scheduler/pause")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestAdminHandler_Trigger(t *testing.T) {
+	h := admin.NewAdminHandler(messaging.NewThroughputTracker(), admin.WithToken(token), admin.WithScheduler(newTestScheduler(nil)))
+
+	rec := serve(h.HandleTrigger, http.MethodPost, "/admin/scheduler/trigger?id=abc")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(h.HandleTrigger, http.MethodPost, "/admin/scheduler/trigger?id=1")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = serve(h.HandleTrigger, http.MethodPost, "/admin/scheduler/trigger")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestAdminHandler_TriggerReachesTheRunningReplica(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	repo := itemRepository{item: dto.PopularDataDto{ID: 1, DataType: "weather", Key: "Moscow", Priority: 1}}
+	newScheduler := func() *background.PriorityScheduler {
+		s := newSchedulerWith(repo, nil)
+		s.SetTriggerStore(scheduler_triggers.NewRedisRepository(client, "scheduler:triggers"))
+		return s
+	}
+	leader, follower := newScheduler(), newScheduler()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go leader.Start(ctx)
+	// The first run is scheduled right away.
+	require.Eventually(t, func() bool { return len(leader.Runs(10)) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	h := admin.NewAdminHandler(messaging.NewThroughputTracker(), admin.WithToken(token), admin.WithScheduler(follower))
+
+	rec := serve(h.HandleTrigger, http.MethodPost, "/admin/scheduler/trigger?id=2")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = serve(h.HandleTrigger, http.MethodPost, "/admin/scheduler/trigger?id=1")
+	require.Equal(t, http.StatusAccepted, rec.Code)
+
+	require.Eventually(t, func() bool { return len(leader.Runs(10)) == 2 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "manual", leader.Runs(1)[0].Trigger)
+
+	rec = serve(h.HandleTrigger, http.MethodPost, "/admin/scheduler/trigger")
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	assert.JSONEq(t, `{"triggered":1}`, rec.Body.String())
+
+	require.Eventually(t, func() bool { return len(leader.Runs(10)) == 3 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestAdminHandler_Runs(t *testing.T) {
+	h := admin.NewAdminHandler(messaging.NewThroughputTracker(), admin.WithScheduler(newTestScheduler(nil)))
+
+	rec := serve(h.HandleRuns, http.MethodGet, "/admin/scheduler/runs?limit=0")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(h.HandleRuns, http.MethodGet, "/admin/scheduler/runs?limit=5&type=weather")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"runs":[]}`, rec.Body.String())
+}
+
+func TestAdminHandler_WithoutScheduler(t *testing.T) {
+	h := admin.NewAdminHandler(messaging.NewThroughputTracker())
+
+	rec := serve(h.HandlePause, http.MethodPost, "/admin/scheduler/pause")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = serve(h.HandleScheduler, http.MethodGet, "/admin/scheduler")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{}`, rec.Body.String())
+}
@@ -0,0 +1,39 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type HealthHandler struct {
+	readiness *Readiness
+}
+
+func NewHealthHandler(readiness *Readiness) *HealthHandler {
+	return &HealthHandler{
+		readiness: readiness,
+	}
+}
+
+func (h *HealthHandler) HandleLive(w http.ResponseWriter, r *http.Request) {
+	responseWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (h *HealthHandler) HandleReady(w http.ResponseWriter, r *http.Request) {
+	ready, pending := h.readiness.Status()
+	if !ready {
+		responseWithJSON(w, http.StatusServiceUnavailable, map[string]any{
+			"status":  "not ready",
+			"pending": pending,
+		})
+		return
+	}
+
+	responseWithJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+func responseWithJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(payload)
+}
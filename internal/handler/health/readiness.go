@@ -0,0 +1,39 @@
+package health
+
+import "sync"
+
+// Readiness tracks the startup and runtime conditions that keep the instance
+// from serving traffic. The instance is ready when no condition is pending.
+type Readiness struct {
+	mu      sync.RWMutex
+	pending map[string]string
+}
+
+func NewReadiness() *Readiness {
+	return &Readiness{
+		pending: make(map[string]string),
+	}
+}
+
+func (r *Readiness) NotReady(name, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending[name] = reason
+}
+
+func (r *Readiness) Ready(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pending, name)
+}
+
+func (r *Readiness) Status() (bool, map[string]string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pending := make(map[string]string, len(r.pending))
+	for k, v := range r.pending {
+		pending[k] = v
+	}
+	return len(pending) == 0, pending
+}
@@ -2,6 +2,7 @@ package popular_data
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -79,6 +80,10 @@ func (h *PopularDataHandler) create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	result, err := h.service.Create(r.Context(), &input)
+	if errors.Is(err, popular_data.ErrInvalidTiming) {
+		responseWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
 		responseWithError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -96,6 +101,10 @@ func (h *PopularDataHandler) update(w http.ResponseWriter, r *http.Request, id i
 	}
 
 	updated, err := h.service.Update(r.Context(), id, &input)
+	if errors.Is(err, popular_data.ErrInvalidTiming) {
+		responseWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
 		responseWithError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -0,0 +1,59 @@
+package popularity
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"service-info-aggregator/internal/repository/popularity"
+)
+
+const (
+	defaultTopN = 10
+	maxTopN     = 1000
+)
+
+type PopularityHandler struct {
+	repo *popularity.RedisRepository
+}
+
+func NewPopularityHandler(repo *popularity.RedisRepository) *PopularityHandler {
+	return &PopularityHandler{
+		repo: repo,
+	}
+}
+
+func (h *PopularityHandler) HandleTop(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	n := defaultTopN
+	if raw := r.URL.Query().Get("n"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxTopN {
+			responseWithError(w, http.StatusBadRequest, "n must be between 1 and 1000")
+			return
+		}
+		n = parsed
+	}
+
+	top, err := h.repo.Top(r.Context(), n)
+	if err != nil {
+		responseWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	responseWithJSON(w, http.StatusOK, top)
+}
+
+func responseWithJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(payload)
+}
+
+func responseWithError(w http.ResponseWriter, statusCode int, message string) {
+	responseWithJSON(w, statusCode, map[string]string{"error": message})
+}
@@ -2,10 +2,12 @@ package weather
 
 import (
 	"encoding/json"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"service-info-aggregator/internal/repository/aggregation_data"
+	"service-info-aggregator/internal/repository/popularity"
 	"service-info-aggregator/internal/service/aggregation"
 )
 
@@ -13,15 +15,17 @@ type WeatherHandler struct {
 	aggregationService *aggregation.AggregationService
 	weatherProvider    *aggregation.WeatherProvider
 	cache              *aggregation_data.RedisRepository
+	popularity         *popularity.RedisRepository
 	ttl                time.Duration
 }
 
 func NewWeatherHandler(aggregationService *aggregation.AggregationService, weatherProvider *aggregation.WeatherProvider,
-	repo *aggregation_data.RedisRepository, ttl time.Duration) *WeatherHandler {
+	repo *aggregation_data.RedisRepository, popularityRepo *popularity.RedisRepository, ttl time.Duration) *WeatherHandler {
 	return &WeatherHandler{
 		aggregationService: aggregationService,
 		weatherProvider:    weatherProvider,
 		cache:              repo,
+		popularity:         popularityRepo,
 		ttl:                ttl,
 	}
 }
@@ -43,6 +47,10 @@ func (h *WeatherHandler) handleGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := h.popularity.Record(ctx, h.weatherProvider.Name(), city); err != nil {
+		slog.WarnContext(ctx, "failed to record request", "type", h.weatherProvider.Name(), "key", city, "error", err)
+	}
+
 	key := h.weatherProvider.CacheKey(city)
 
 	if cached, err := h.cache.Get(ctx, key); err == nil {
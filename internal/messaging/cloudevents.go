@@ -0,0 +1,143 @@
+package messaging
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"service-info-aggregator/internal/model/events"
+)
+
+const (
+	EventModeStructured = "structured"
+	EventModeBinary     = "binary"
+
+	legacyEventSource = "legacy"
+
+	contentTypeHeader     = "content-type"
+	cloudEventsJSONType   = "application/cloudevents+json"
+	cloudEventsHeaderBase = "ce_"
+
+	headerID          = cloudEventsHeaderBase + "id"
+	headerSource      = cloudEventsHeaderBase + "source"
+	headerSpecVersion = cloudEventsHeaderBase + "specversion"
+	headerType        = cloudEventsHeaderBase + "type"
+	headerSubject     = cloudEventsHeaderBase + "subject"
+	headerTime        = cloudEventsHeaderBase + "time"
+	headerDataSchema  = cloudEventsHeaderBase + "dataschema"
+	headerFencing     = cloudEventsHeaderBase + "fencingtoken"
+)
+
+func EncodeCloudEvent(topic string, event *events.CloudEvent, mode string) (*Message, error) {
+	msg := &Message{
+		Topic: topic,
+		Key:   []byte(event.Subject),
+	}
+
+	switch mode {
+	case EventModeStructured, "":
+		value, err := json.Marshal(event)
+		if err != nil {
+			return nil, fmt.Errorf("could not marshal cloudevent: %w", err)
+		}
+		msg.Value = value
+		msg.Headers = []Header{{Key: contentTypeHeader, Value: []byte(cloudEventsJSONType)}}
+	case EventModeBinary:
+		msg.Value = event.Data
+		msg.Headers = []Header{
+			{Key: headerID, Value: []byte(event.ID)},
+			{Key: headerSource, Value: []byte(event.Source)},
+			{Key: headerSpecVersion, Value: []byte(event.SpecVersion)},
+			{Key: headerType, Value: []byte(event.Type)},
+			{Key: headerTime, Value: []byte(event.Time.UTC().Format(time.RFC3339Nano))},
+		}
+		if event.Subject != "" {
+			msg.Headers = append(msg.Headers, Header{Key: headerSubject, Value: []byte(event.Subject)})
+		}
+		if event.DataSchema != "" {
+			msg.Headers = append(msg.Headers, Header{Key: headerDataSchema, Value: []byte(event.DataSchema)})
+		}
+		if event.FencingToken != 0 {
+			msg.Headers = append(msg.Headers, Header{Key: headerFencing, Value: []byte(strconv.FormatInt(event.FencingToken, 10))})
+		}
+		if event.DataContentType != "" {
+			msg.Headers = append(msg.Headers, Header{Key: contentTypeHeader, Value: []byte(event.DataContentType)})
+		}
+	default:
+		return nil, fmt.Errorf("unknown cloudevents mode: %s", mode)
+	}
+
+	return msg, nil
+}
+
+// DecodeCloudEvent accepts binary and structured CloudEvents as well as the
+// legacy GenericUpdatedEvent JSON published before the envelope existed.
+func DecodeCloudEvent(msg *Message) (*events.CloudEvent, error) {
+	headers := make(map[string]string, len(msg.Headers))
+	for _, h := range msg.Headers {
+		headers[strings.ToLower(h.Key)] = string(h.Value)
+	}
+
+	if _, ok := headers[headerSpecVersion]; ok {
+		return decodeBinaryCloudEvent(headers, msg.Value)
+	}
+
+	var probe struct {
+		SpecVersion string `json:"specversion"`
+	}
+	if err := json.Unmarshal(msg.Value, &probe); err != nil {
+		return nil, fmt.Errorf("could not unmarshal event: %w", err)
+	}
+
+	if probe.SpecVersion != "" || strings.HasPrefix(headers[contentTypeHeader], cloudEventsJSONType) {
+		var event events.CloudEvent
+		if err := json.Unmarshal(msg.Value, &event); err != nil {
+			return nil, fmt.Errorf("could not unmarshal cloudevent: %w", err)
+		}
+		if err := event.Validate(); err != nil {
+			return nil, err
+		}
+		return &event, nil
+	}
+
+	var legacy events.GenericUpdatedEvent
+	if err := json.Unmarshal(msg.Value, &legacy); err != nil {
+		return nil, fmt.Errorf("could not unmarshal legacy event: %w", err)
+	}
+	return legacy.ToCloudEvent(legacyEventSource)
+}
+
+func decodeBinaryCloudEvent(headers map[string]string, value []byte) (*events.CloudEvent, error) {
+	event := &events.CloudEvent{
+		ID:              headers[headerID],
+		Source:          headers[headerSource],
+		SpecVersion:     headers[headerSpecVersion],
+		Type:            headers[headerType],
+		Subject:         headers[headerSubject],
+		DataContentType: headers[contentTypeHeader],
+		DataSchema:      headers[headerDataSchema],
+		Data:            value,
+	}
+
+	if raw, ok := headers[headerTime]; ok {
+		t, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s header: %w", headerTime, err)
+		}
+		event.Time = t
+	}
+	if raw, ok := headers[headerFencing]; ok {
+		token, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s header: %w", headerFencing, err)
+		}
+		event.FencingToken = token
+	}
+
+	if err := event.Validate(); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
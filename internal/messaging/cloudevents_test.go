@@ -0,0 +1,81 @@
+package messaging_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"service-info-aggregator/internal/messaging"
+	"service-info-aggregator/internal/model/dto"
+	"service-info-aggregator/internal/model/events"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEvent(t *testing.T) *events.CloudEvent {
+	event, err := events.NewCloudEvent("/test", "weather", "Moscow", dto.WeatherResponse{City: "Moscow", Temp: 20})
+	require.NoError(t, err)
+	return event
+}
+
+func TestCloudEvents_Structured_RoundTrip(t *testing.T) {
+	event := newTestEvent(t)
+
+	msg, err := messaging.EncodeCloudEvent("events", event, messaging.EventModeStructured)
+	require.NoError(t, err)
+	assert.Equal(t, "Moscow", string(msg.Key))
+
+	decoded, err := messaging.DecodeCloudEvent(msg)
+	require.NoError(t, err)
+	assert.Equal(t, event.ID, decoded.ID)
+	assert.Equal(t, "weather", decoded.Type)
+	assert.Equal(t, "Moscow", decoded.Subject)
+	assert.True(t, event.Time.Equal(decoded.Time))
+	assert.JSONEq(t, string(event.Data), string(decoded.Data))
+}
+
+func TestCloudEvents_Binary_RoundTrip(t *testing.T) {
+	event := newTestEvent(t)
+	event.FencingToken = 7
+
+	msg, err := messaging.EncodeCloudEvent("events", event, messaging.EventModeBinary)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"city":"Moscow","temp":20}`, string(msg.Value))
+
+	decoded, err := messaging.DecodeCloudEvent(msg)
+	require.NoError(t, err)
+	assert.Equal(t, event.ID, decoded.ID)
+	assert.Equal(t, "/test", decoded.Source)
+	assert.Equal(t, events.ContentTypeJSON, decoded.DataContentType)
+	assert.Equal(t, int64(7), decoded.FencingToken)
+	assert.True(t, event.Time.Equal(decoded.Time))
+}
+
+func TestCloudEvents_Legacy(t *testing.T) {
+	legacy := events.GenericUpdatedEvent{
+		Type:      "weather",
+		Key:       "Berlin",
+		Payload:   dto.WeatherResponse{City: "Berlin", Temp: 15},
+		Timestamp: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+	value, err := json.Marshal(legacy)
+	require.NoError(t, err)
+
+	first, err := messaging.DecodeCloudEvent(&messaging.Message{Value: value})
+	require.NoError(t, err)
+	second, err := messaging.DecodeCloudEvent(&messaging.Message{Value: value})
+	require.NoError(t, err)
+
+	assert.Equal(t, "weather", first.Type)
+	assert.Equal(t, "Berlin", first.Subject)
+	assert.Equal(t, first.ID, second.ID)
+	assert.JSONEq(t, `{"city":"Berlin","temp":15}`, string(first.Data))
+}
+
+func TestCloudEvents_UnsupportedSpecVersion(t *testing.T) {
+	_, err := messaging.DecodeCloudEvent(&messaging.Message{
+		Value: []byte(`{"specversion":"0.3","id":"1","source":"/x","type":"weather"}`),
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,77 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Deduplicator claims event IDs atomically, so that concurrent deliveries of
+// one event are handled once. Claims expire unless confirmed.
+type Deduplicator interface {
+	Claim(ctx context.Context, eventID string, ttl time.Duration) (bool, error)
+	Confirm(ctx context.Context, eventID string, ttl time.Duration) error
+	Release(ctx context.Context, eventID string) error
+}
+
+type Dispatcher struct {
+	serializer EventSerializer
+	router     *EventRouter
+	dedup      Deduplicator
+	claimTTL   time.Duration
+	dedupTTL   time.Duration
+}
+
+// NewDispatcher creates a dispatcher. An event is claimed for claimTTL while
+// it is handled, which should cover the longest handling, and remembered for
+// dedupTTL once it was handled.
+func NewDispatcher(serializer EventSerializer, router *EventRouter, dedup Deduplicator, claimTTL, dedupTTL time.Duration) *Dispatcher {
+	return &Dispatcher{
+		serializer: serializer,
+		router:     router,
+		dedup:      dedup,
+		claimTTL:   claimTTL,
+		dedupTTL:   dedupTTL,
+	}
+}
+
+func (d *Dispatcher) Dispatch(ctx context.Context, msg *Message) error {
+	if eventType, ok := msg.Header(EventTypeHeader); ok && !d.router.Handles(eventType) {
+		slog.DebugContext(ctx, "event type not handled, skipping", "type", eventType, "topic", msg.Topic, "offset", msg.Offset)
+		return nil
+	}
+
+	event, err := d.serializer.Deserialize(ctx, msg)
+	if err != nil {
+		return err
+	}
+
+	if d.dedup == nil {
+		return d.router.Route(ctx, event)
+	}
+
+	claimed, err := d.dedup.Claim(ctx, event.ID, d.claimTTL)
+	if err != nil {
+		return fmt.Errorf("could not check event %s for duplicates: %w", event.ID, err)
+	}
+	if !claimed {
+		slog.InfoContext(ctx, "duplicate event skipped", "id", event.ID, "type", event.Type, "subject", event.Subject)
+		return nil
+	}
+
+	if err := d.router.Route(ctx, event); err != nil {
+		// Let the redelivery of a failed event through.
+		if releaseErr := d.dedup.Release(context.WithoutCancel(ctx), event.ID); releaseErr != nil {
+			slog.ErrorContext(ctx, "failed to release event claim", "id", event.ID, "error", releaseErr)
+		}
+		return err
+	}
+
+	if err := d.dedup.Confirm(context.WithoutCancel(ctx), event.ID, d.dedupTTL); err != nil {
+		// The claim expires and a redelivery is applied once more, which
+		// the cache tolerates.
+		slog.ErrorContext(ctx, "failed to confirm processed event", "id", event.ID, "error", err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package messaging_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"service-info-aggregator/internal/messaging"
+	"service-info-aggregator/internal/repository/processed_events"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testClaimTTL = time.Minute
+	testDedupTTL = 24 * time.Hour
+)
+
+func newDedupDispatcher(t *testing.T, handler *recordingHandler) (*messaging.Dispatcher, *processed_events.RedisRepository, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	repo := processed_events.NewRedisRepository(client)
+	router := messaging.NewEventRouter(messaging.WithHandlers(handler))
+	dispatcher := messaging.NewDispatcher(messaging.NewJSONSerializer(messaging.EventModeStructured), router, repo, testClaimTTL, testDedupTTL)
+	return dispatcher, repo, server
+}
+
+func weatherMessage(t *testing.T) *messaging.Message {
+	msg, err := messaging.EncodeCloudEvent("events", testEvent("weather"), messaging.EventModeStructured)
+	require.NoError(t, err)
+	return msg
+}
+
+func TestDispatcher_SkipsHandledEvents(t *testing.T) {
+	var calls []string
+	dispatcher, _, server := newDedupDispatcher(t, &recordingHandler{eventType: "weather", name: "weather", calls: &calls})
+	ctx := context.Background()
+
+	require.NoError(t, dispatcher.Dispatch(ctx, weatherMessage(t)))
+	server.FastForward(2 * testClaimTTL)
+	require.NoError(t, dispatcher.Dispatch(ctx, weatherMessage(t)))
+
+	assert.Equal(t, []string{"weather"}, calls)
+}
+
+func TestDispatcher_HandlesEventAgainAfterAbandonedClaim(t *testing.T) {
+	var calls []string
+	dispatcher, repo, server := newDedupDispatcher(t, &recordingHandler{eventType: "weather", name: "weather", calls: &calls})
+	ctx := context.Background()
+
+	// A replica claimed the event and crashed before handling it.
+	claimed, err := repo.Claim(ctx, testEvent("weather").ID, testClaimTTL)
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	require.NoError(t, dispatcher.Dispatch(ctx, weatherMessage(t)))
+	assert.Empty(t, calls, "the claim is still in progress")
+
+	server.FastForward(testClaimTTL)
+	require.NoError(t, dispatcher.Dispatch(ctx, weatherMessage(t)))
+	assert.Equal(t, []string{"weather"}, calls)
+}
+
+func TestDispatcher_ReleasesClaimWhenHandlingFails(t *testing.T) {
+	var calls []string
+	handler := &recordingHandler{eventType: "weather", name: "weather", calls: &calls, err: errors.New("redis down")}
+	dispatcher, _, _ := newDedupDispatcher(t, handler)
+	ctx := context.Background()
+
+	require.Error(t, dispatcher.Dispatch(ctx, weatherMessage(t)))
+	handler.err = nil
+	require.NoError(t, dispatcher.Dispatch(ctx, weatherMessage(t)))
+
+	assert.Equal(t, []string{"weather", "weather"}, calls)
+}
@@ -0,0 +1,12 @@
+package messaging
+
+import (
+	"context"
+
+	"service-info-aggregator/internal/model/events"
+)
+
+type EventHandler interface {
+	Type() string
+	Handle(ctx context.Context, event *events.CloudEvent) error
+}
@@ -0,0 +1,133 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"service-info-aggregator/internal/model/events"
+)
+
+type HandlerFunc func(ctx context.Context, event *events.CloudEvent) error
+
+type Middleware func(next HandlerFunc) HandlerFunc
+
+type RouterOption func(*routerConfig)
+
+type route struct {
+	pattern string
+	handler EventHandler
+}
+
+type routerConfig struct {
+	routes         []route
+	defaultHandler EventHandler
+	middlewares    []Middleware
+}
+
+// WithHandlers registers every handler under the pattern returned by its
+// Type method.
+func WithHandlers(handlers ...EventHandler) RouterOption {
+	return func(c *routerConfig) {
+		for _, h := range handlers {
+			c.routes = append(c.routes, route{pattern: h.Type(), handler: h})
+		}
+	}
+}
+
+// WithRoute registers a handler for a pattern: an exact event type, a prefix
+// ending in "*" such as "weather.*", or "*" for every event.
+func WithRoute(pattern string, h EventHandler) RouterOption {
+	return func(c *routerConfig) {
+		c.routes = append(c.routes, route{pattern: pattern, handler: h})
+	}
+}
+
+// WithDefaultHandler sets the handler for events no route matches.
+func WithDefaultHandler(h EventHandler) RouterOption {
+	return func(c *routerConfig) {
+		c.defaultHandler = h
+	}
+}
+
+// WithMiddleware appends middlewares; the first one is the outermost.
+func WithMiddleware(mw ...Middleware) RouterOption {
+	return func(c *routerConfig) {
+		c.middlewares = append(c.middlewares, mw...)
+	}
+}
+
+type prefixRoute struct {
+	prefix  string
+	handler HandlerFunc
+}
+
+type EventRouter struct {
+	exact          map[string][]HandlerFunc
+	prefixes       []prefixRoute
+	defaultHandler HandlerFunc
+}
+
+func NewEventRouter(opts ...RouterOption) *EventRouter {
+	var cfg routerConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	wrap := func(h EventHandler) HandlerFunc {
+		fn := HandlerFunc(h.Handle)
+		for i := len(cfg.middlewares) - 1; i >= 0; i-- {
+			fn = cfg.middlewares[i](fn)
+		}
+		return fn
+	}
+
+	r := &EventRouter{exact: make(map[string][]HandlerFunc)}
+	for _, rt := range cfg.routes {
+		if prefix, ok := strings.CutSuffix(rt.pattern, "*"); ok {
+			r.prefixes = append(r.prefixes, prefixRoute{prefix: prefix, handler: wrap(rt.handler)})
+			continue
+		}
+		r.exact[rt.pattern] = append(r.exact[rt.pattern], wrap(rt.handler))
+	}
+	if cfg.defaultHandler != nil {
+		r.defaultHandler = wrap(cfg.defaultHandler)
+	}
+
+	return r
+}
+
+// Route delivers the event to every matching handler and joins their errors.
+func (r *EventRouter) Route(cxt context.Context, event *events.CloudEvent) error {
+	handlers := r.match(event.Type)
+	if len(handlers) == 0 {
+		if r.defaultHandler == nil {
+			return fmt.Errorf("no handler for event type: %s", event.Type)
+		}
+		handlers = []HandlerFunc{r.defaultHandler}
+	}
+
+	var errs []error
+	for _, h := range handlers {
+		if err := h(cxt, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Handles reports whether Route would deliver an event of this type anywhere.
+func (r *EventRouter) Handles(eventType string) bool {
+	return r.defaultHandler != nil || len(r.match(eventType)) > 0
+}
+
+func (r *EventRouter) match(eventType string) []HandlerFunc {
+	handlers := r.exact[eventType]
+	for _, p := range r.prefixes {
+		if strings.HasPrefix(eventType, p.prefix) {
+			handlers = append(handlers[:len(handlers):len(handlers)], p.handler)
+		}
+	}
+	return handlers
+}
@@ -0,0 +1,123 @@
+package messaging_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"service-info-aggregator/internal/messaging"
+	"service-info-aggregator/internal/model/events"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingHandler struct {
+	eventType string
+	calls     *[]string
+	name      string
+	err       error
+	panics    bool
+}
+
+func (h *recordingHandler) Type() string { return h.eventType }
+
+func (h *recordingHandler) Handle(ctx context.Context, event *events.CloudEvent) error {
+	*h.calls = append(*h.calls, h.name)
+	if h.panics {
+		panic("boom")
+	}
+	return h.err
+}
+
+func testEvent(eventType string) *events.CloudEvent {
+	return &events.CloudEvent{
+		ID:          "1",
+		Source:      "/test",
+		SpecVersion: events.CloudEventsSpecVersion,
+		Type:        eventType,
+		Subject:     "Moscow",
+		Time:        time.Now(),
+	}
+}
+
+func TestEventRouter_FanOutAndWildcards(t *testing.T) {
+	var calls []string
+	router := messaging.NewEventRouter(
+		messaging.WithHandlers(
+			&recordingHandler{eventType: "weather.updated", name: "exact-1", calls: &calls},
+			&recordingHandler{eventType: "weather.updated", name: "exact-2", calls: &calls},
+		),
+		messaging.WithRoute("weather.*", &recordingHandler{name: "prefix", calls: &calls}),
+		messaging.WithRoute("*", &recordingHandler{name: "all", calls: &calls}),
+		messaging.WithRoute("news.*", &recordingHandler{name: "news", calls: &calls}),
+	)
+
+	require.NoError(t, router.Route(context.Background(), testEvent("weather.updated")))
+	assert.Equal(t, []string{"exact-1", "exact-2", "prefix", "all"}, calls)
+}
+
+func TestEventRouter_DefaultHandler(t *testing.T) {
+	var calls []string
+	withDefault := messaging.NewEventRouter(
+		messaging.WithHandlers(&recordingHandler{eventType: "weather", name: "weather", calls: &calls}),
+		messaging.WithDefaultHandler(&recordingHandler{name: "default", calls: &calls}),
+	)
+	require.NoError(t, withDefault.Route(context.Background(), testEvent("news")))
+	assert.Equal(t, []string{"default"}, calls)
+
+	withoutDefault := messaging.NewEventRouter(
+		messaging.WithHandlers(&recordingHandler{eventType: "weather", name: "weather", calls: &calls}),
+	)
+	require.Error(t, withoutDefault.Route(context.Background(), testEvent("news")))
+}
+
+func TestEventRouter_JoinsHandlerErrors(t *testing.T) {
+	var calls []string
+	first, second := errors.New("first"), errors.New("second")
+	router := messaging.NewEventRouter(
+		messaging.WithRoute("weather", &recordingHandler{name: "a", calls: &calls, err: first}),
+		messaging.WithRoute("weather", &recordingHandler{name: "b", calls: &calls, err: second}),
+	)
+
+	err := router.Route(context.Background(), testEvent("weather"))
+	assert.ErrorIs(t, err, first)
+	assert.ErrorIs(t, err, second)
+	assert.Len(t, calls, 2)
+}
+
+func TestEventRouter_MiddlewareOrder(t *testing.T) {
+	var calls []string
+	tag := func(name string) messaging.Middleware {
+		return func(next messaging.HandlerFunc) messaging.HandlerFunc {
+			return func(ctx context.Context, event *events.CloudEvent) error {
+				calls = append(calls, name)
+				return next(ctx, event)
+			}
+		}
+	}
+
+	router := messaging.NewEventRouter(
+		messaging.WithHandlers(&recordingHandler{eventType: "weather", name: "handler", calls: &calls}),
+		messaging.WithMiddleware(tag("outer"), tag("inner")),
+	)
+
+	require.NoError(t, router.Route(context.Background(), testEvent("weather")))
+	assert.Equal(t, []string{"outer", "inner", "handler"}, calls)
+}
+
+func TestEventRouter_RecoveryAndValidation(t *testing.T) {
+	var calls []string
+	router := messaging.NewEventRouter(
+		messaging.WithHandlers(&recordingHandler{eventType: "weather", name: "handler", calls: &calls, panics: true}),
+		messaging.WithMiddleware(messaging.RecoveryMiddleware, messaging.ValidationMiddleware),
+	)
+
+	require.ErrorContains(t, router.Route(context.Background(), testEvent("weather")), "panicked")
+
+	invalid := testEvent("weather")
+	invalid.Subject = ""
+	require.Error(t, router.Route(context.Background(), invalid))
+	assert.Len(t, calls, 1)
+}
@@ -0,0 +1,63 @@
+package kafka
+
+import (
+	"fmt"
+	"strings"
+
+	"service-info-aggregator/internal/config"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// newConfigMap builds the librdkafka configuration shared by every client:
+// brokers and security settings first, then the client-specific settings,
+// then the raw overrides from the environment.
+func newConfigMap(cfg *config.KafkaConfig, clientID string, settings kafka.ConfigMap) (*kafka.ConfigMap, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, fmt.Errorf("no kafka brokers configured")
+	}
+
+	cm := kafka.ConfigMap{
+		"bootstrap.servers": strings.Join(cfg.Brokers, ","),
+		"client.id":         clientID,
+		"security.protocol": cfg.SecurityProtocol,
+	}
+
+	protocol := strings.ToUpper(cfg.SecurityProtocol)
+
+	if strings.HasPrefix(protocol, "SASL_") {
+		switch strings.ToUpper(cfg.SASLMechanism) {
+		case "PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512":
+		default:
+			return nil, fmt.Errorf("unsupported kafka sasl mechanism: %q", cfg.SASLMechanism)
+		}
+		cm["sasl.mechanisms"] = strings.ToUpper(cfg.SASLMechanism)
+		cm["sasl.username"] = cfg.SASLUsername
+		cm["sasl.password"] = cfg.SASLPassword
+	}
+
+	if protocol == "SSL" || protocol == "SASL_SSL" {
+		if cfg.TLSCAFile != "" {
+			cm["ssl.ca.location"] = cfg.TLSCAFile
+		}
+		if cfg.TLSCertFile != "" {
+			cm["ssl.certificate.location"] = cfg.TLSCertFile
+			cm["ssl.key.location"] = cfg.TLSKeyFile
+		}
+		if cfg.TLSKeyPassword != "" {
+			cm["ssl.key.password"] = cfg.TLSKeyPassword
+		}
+		if cfg.TLSSkipVerify {
+			cm["enable.ssl.certificate.verification"] = false
+		}
+	}
+
+	for k, v := range settings {
+		cm[k] = v
+	}
+	for k, v := range cfg.Overrides {
+		cm[k] = v
+	}
+
+	return &cm, nil
+}
@@ -0,0 +1,102 @@
+package kafka_test
+
+import (
+	"testing"
+
+	"service-info-aggregator/internal/config"
+	"service-info-aggregator/internal/messaging/kafka"
+
+	confluent "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewConfigMap_Plaintext(t *testing.T) {
+	cm, err := kafka.NewConfigMap(&config.KafkaConfig{
+		Brokers:          []string{"broker-1:9092", "broker-2:9092"},
+		SecurityProtocol: "PLAINTEXT",
+		SASLUsername:     "ignored",
+		TLSCAFile:        "/ignored.pem",
+	}, "aggregator-consumer", confluent.ConfigMap{"group.id": "aggregator-consumer"})
+	require.NoError(t, err)
+
+	assert.Equal(t, confluent.ConfigMap{
+		"bootstrap.servers": "broker-1:9092,broker-2:9092",
+		"client.id":         "aggregator-consumer",
+		"security.protocol": "PLAINTEXT",
+		"group.id":          "aggregator-consumer",
+	}, *cm)
+}
+
+func TestNewConfigMap_SASLAndTLS(t *testing.T) {
+	cm, err := kafka.NewConfigMap(&config.KafkaConfig{
+		Brokers:          []string{"broker:9093"},
+		SecurityProtocol: "SASL_SSL",
+		SASLMechanism:    "scram-sha-512",
+		SASLUsername:     "aggregator",
+		SASLPassword:     "secret",
+		TLSCAFile:        "/ca.pem",
+		TLSCertFile:      "/client.pem",
+		TLSKeyFile:       "/client.key",
+		TLSKeyPassword:   "key-secret",
+		TLSSkipVerify:    true,
+	}, "aggregator", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, confluent.ConfigMap{
+		"bootstrap.servers":                   "broker:9093",
+		"client.id":                           "aggregator",
+		"security.protocol":                   "SASL_SSL",
+		"sasl.mechanisms":                     "SCRAM-SHA-512",
+		"sasl.username":                       "aggregator",
+		"sasl.password":                       "secret",
+		"ssl.ca.location":                     "/ca.pem",
+		"ssl.certificate.location":            "/client.pem",
+		"ssl.key.location":                    "/client.key",
+		"ssl.key.password":                    "key-secret",
+		"enable.ssl.certificate.verification": false,
+	}, *cm)
+}
+
+func TestNewConfigMap_SSLWithoutSASL(t *testing.T) {
+	cm, err := kafka.NewConfigMap(&config.KafkaConfig{
+		Brokers:          []string{"broker:9093"},
+		SecurityProtocol: "ssl",
+		SASLMechanism:    "PLAIN",
+		TLSCAFile:        "/ca.pem",
+	}, "aggregator", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "/ca.pem", (*cm)["ssl.ca.location"])
+	assert.NotContains(t, *cm, "sasl.mechanisms")
+	assert.NotContains(t, *cm, "ssl.certificate.location")
+}
+
+func TestNewConfigMap_OverridesWin(t *testing.T) {
+	cm, err := kafka.NewConfigMap(&config.KafkaConfig{
+		Brokers:          []string{"broker:9092"},
+		SecurityProtocol: "PLAINTEXT",
+		Overrides: map[string]string{
+			"auto.offset.reset": "latest",
+			"security.protocol": "SSL",
+			"fetch.min.bytes":   "1024",
+		},
+	}, "aggregator", confluent.ConfigMap{"auto.offset.reset": "earliest"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "latest", (*cm)["auto.offset.reset"])
+	assert.Equal(t, "SSL", (*cm)["security.protocol"])
+	assert.Equal(t, "1024", (*cm)["fetch.min.bytes"])
+}
+
+func TestNewConfigMap_Errors(t *testing.T) {
+	_, err := kafka.NewConfigMap(&config.KafkaConfig{SecurityProtocol: "PLAINTEXT"}, "aggregator", nil)
+	require.Error(t, err)
+
+	_, err = kafka.NewConfigMap(&config.KafkaConfig{
+		Brokers:          []string{"broker:9092"},
+		SecurityProtocol: "SASL_PLAINTEXT",
+		SASLMechanism:    "GSSAPI",
+	}, "aggregator", nil)
+	require.Error(t, err)
+}
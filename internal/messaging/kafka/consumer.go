@@ -2,75 +2,196 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"strings"
 	"time"
 
-	"service-info-aggregator/internal/model/events"
+	"service-info-aggregator/internal/config"
+	"service-info-aggregator/internal/messaging"
+	"service-info-aggregator/internal/metrics"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 )
 
+// consumerClient is the part of *kafka.Consumer that KafkaConsumer and the
+// LagMonitor use.
+type consumerClient interface {
+	SubscribeTopics(topics []string, rebalanceCb kafka.RebalanceCb) error
+	ReadMessage(timeout time.Duration) (*kafka.Message, error)
+	StoreMessage(m *kafka.Message) ([]kafka.TopicPartition, error)
+	Commit() ([]kafka.TopicPartition, error)
+	AssignmentLost() bool
+	Assignment() ([]kafka.TopicPartition, error)
+	Committed(partitions []kafka.TopicPartition, timeoutMs int) ([]kafka.TopicPartition, error)
+	QueryWatermarkOffsets(topic string, partition int32, timeoutMs int) (int64, int64, error)
+	Close() error
+}
+
 type KafkaConsumer struct {
-	consumer *kafka.Consumer
-	router   *EventRouter
+	consumer        consumerClient
+	commitInterval  time.Duration
+	commitBatchSize int
+	uncommitted     int
 }
 
-func NewKafkaConsumer(brokers []string, groupID string, router *EventRouter) (*KafkaConsumer, error) {
-	c, err := kafka.NewConsumer(&kafka.ConfigMap{
-		"bootstrap.servers":  strings.Join(brokers, ","),
-		"group.id":           groupID,
-		"auto.offset.reset":  "earliest",
-		"enable.auto.commit": false,
+func NewKafkaConsumer(cfg *config.KafkaConfig) (*KafkaConsumer, error) {
+	cm, err := newConfigMap(cfg, cfg.ClientID+"-consumer", kafka.ConfigMap{
+		"group.id":                      cfg.GroupID,
+		"auto.offset.reset":             "earliest",
+		"enable.auto.commit":            false,
+		"enable.auto.offset.store":      false,
+		"partition.assignment.strategy": "cooperative-sticky",
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &KafkaConsumer{c, router}, nil
+	c, err := kafka.NewConsumer(cm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KafkaConsumer{
+		consumer:        c,
+		commitInterval:  cfg.CommitInterval,
+		commitBatchSize: cfg.CommitBatchSize,
+	}, nil
 }
 
-func (c *KafkaConsumer) Run(ctx context.Context, topics []string) error {
-	if err := c.consumer.SubscribeTopics(topics, nil); err != nil {
+// Run processes messages until ctx is cancelled or the client hits a fatal
+// error. Offsets of successfully processed messages are stored locally and
+// committed in batches, on rebalance and on shutdown.
+func (c *KafkaConsumer) Run(ctx context.Context, topics []string, handler messaging.MessageHandler) error {
+	if err := c.consumer.SubscribeTopics(topics, c.rebalance); err != nil {
 		return err
 	}
 
+	lastCommit := time.Now()
+	defer c.commit()
+
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
 		default:
-			msg, err := c.consumer.ReadMessage(1 * time.Second)
-			if err != nil {
-				continue
-			}
+		}
 
-			if err := c.processMessage(ctx, msg); err != nil {
-				slog.Error("message processing failed", "error", err)
+		if c.uncommitted > 0 && (c.uncommitted >= c.commitBatchSize || time.Since(lastCommit) >= c.commitInterval) {
+			c.commit()
+			lastCommit = time.Now()
+		}
+
+		msg, err := c.consumer.ReadMessage(1 * time.Second)
+		if err != nil {
+			var kerr kafka.Error
+			if !errors.As(err, &kerr) {
+				return err
+			}
+			if kerr.IsTimeout() {
 				continue
 			}
+			if kerr.IsFatal() {
+				return fmt.Errorf("fatal kafka consumer error: %w", kerr)
+			}
+			slog.Warn("kafka consumer error", "code", kerr.Code(), "error", kerr)
+			continue
+		}
 
-			c.consumer.CommitMessage(msg)
+		metrics.MessagesConsumed.WithLabelValues(*msg.TopicPartition.Topic).Inc()
+
+		if err := c.processMessage(ctx, msg, handler); err != nil {
+			continue
+		}
+
+		if _, err := c.consumer.StoreMessage(msg); err != nil {
+			slog.Error("failed to store offset", "error", err)
+			continue
 		}
+		c.uncommitted++
 	}
 }
 
-func (c *KafkaConsumer) processMessage(ctx context.Context, msg *kafka.Message) error {
-	var event events.GenericUpdatedEvent
+// processMessage runs the handler in the trace and request context carried by
+// the message headers, so its logs line up with the publishing request.
+func (c *KafkaConsumer) processMessage(ctx context.Context, msg *kafka.Message, handler messaging.MessageHandler) error {
+	m := fromKafkaMessage(msg)
+	ctx = messaging.ExtractMetadata(ctx, m)
 
-	if err := json.Unmarshal(msg.Value, &event); err != nil {
+	if err := handler(ctx, m); err != nil {
+		slog.ErrorContext(ctx, "message processing failed",
+			"topic", m.Topic,
+			"partition", m.Partition,
+			"offset", m.Offset,
+			"error", err)
 		return err
 	}
+	return nil
+}
 
-	return c.router.Route(
-		ctx,
-		event.Type,
-		event.Key,
-		event.Payload,
-	)
+func (c *KafkaConsumer) rebalance(_ *kafka.Consumer, ev kafka.Event) error {
+	switch e := ev.(type) {
+	case kafka.AssignedPartitions:
+		slog.Info("kafka partitions assigned", "partitions", formatPartitions(e.Partitions))
+	case kafka.RevokedPartitions:
+		slog.Info("kafka partitions revoked", "partitions", formatPartitions(e.Partitions))
+		// Offsets of lost partitions may already belong to another member.
+		if c.consumer.AssignmentLost() {
+			slog.Warn("kafka partition assignment lost, skipping commit")
+			return nil
+		}
+		c.commit()
+	}
+	return nil
+}
+
+func (c *KafkaConsumer) commit() {
+	if c.uncommitted == 0 {
+		return
+	}
+
+	if _, err := c.consumer.Commit(); err != nil {
+		var kerr kafka.Error
+		if errors.As(err, &kerr) && kerr.Code() == kafka.ErrNoOffset {
+			c.uncommitted = 0
+			return
+		}
+		slog.Error("failed to commit offsets", "error", err)
+		return
+	}
+	c.uncommitted = 0
 }
 
 func (c *KafkaConsumer) Close() {
 	c.consumer.Close()
 }
+
+func formatPartitions(partitions []kafka.TopicPartition) string {
+	parts := make([]string, 0, len(partitions))
+	for _, p := range partitions {
+		topic := ""
+		if p.Topic != nil {
+			topic = *p.Topic
+		}
+		parts = append(parts, fmt.Sprintf("%s[%d]", topic, p.Partition))
+	}
+	return strings.Join(parts, ",")
+}
+
+func fromKafkaMessage(msg *kafka.Message) *messaging.Message {
+	m := &messaging.Message{
+		Partition: msg.TopicPartition.Partition,
+		Offset:    int64(msg.TopicPartition.Offset),
+		Key:       msg.Key,
+		Value:     msg.Value,
+		Timestamp: msg.Timestamp,
+	}
+	if msg.TopicPartition.Topic != nil {
+		m.Topic = *msg.TopicPartition.Topic
+	}
+	for _, h := range msg.Headers {
+		m.Headers = append(m.Headers, messaging.Header{Key: h.Key, Value: h.Value})
+	}
+	return m
+}
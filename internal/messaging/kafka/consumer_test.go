@@ -0,0 +1,198 @@
+package kafka_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"service-info-aggregator/internal/messaging"
+	"service-info-aggregator/internal/messaging/kafka"
+
+	confluent "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ kafka.ConsumerClient = (*fakeClient)(nil)
+
+// fakeClient plays back steps, one per ReadMessage, and cancels the run once
+// they are used up.
+type fakeClient struct {
+	mu        sync.Mutex
+	steps     []func(c *fakeClient) (*confluent.Message, error)
+	rebalance confluent.RebalanceCb
+	lost      bool
+	stored    []int64
+	commits   []int
+	done      context.CancelFunc
+}
+
+func (c *fakeClient) SubscribeTopics(topics []string, cb confluent.RebalanceCb) error {
+	c.rebalance = cb
+	return nil
+}
+
+func (c *fakeClient) ReadMessage(timeout time.Duration) (*confluent.Message, error) {
+	if len(c.steps) == 0 {
+		c.done()
+		return nil, timeoutError()
+	}
+	step := c.steps[0]
+	c.steps = c.steps[1:]
+	return step(c)
+}
+
+func (c *fakeClient) StoreMessage(m *confluent.Message) ([]confluent.TopicPartition, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stored = append(c.stored, int64(m.TopicPartition.Offset))
+	return nil, nil
+}
+
+// Commit records how many offsets were stored when it was called.
+func (c *fakeClient) Commit() ([]confluent.TopicPartition, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.commits = append(c.commits, len(c.stored))
+	return nil, nil
+}
+
+func (c *fakeClient) AssignmentLost() bool { return c.lost }
+
+func (c *fakeClient) Assignment() ([]confluent.TopicPartition, error) { return nil, nil }
+
+func (c *fakeClient) Committed(partitions []confluent.TopicPartition, timeoutMs int) ([]confluent.TopicPartition, error) {
+	return partitions, nil
+}
+
+func (c *fakeClient) QueryWatermarkOffsets(topic string, partition int32, timeoutMs int) (int64, int64, error) {
+	return 0, 0, nil
+}
+
+func (c *fakeClient) Close() error { return nil }
+
+func timeoutError() error {
+	return confluent.NewError(confluent.ErrTimedOut, "timed out", false)
+}
+
+func message(offset int64) func(c *fakeClient) (*confluent.Message, error) {
+	topic := "events"
+	return func(c *fakeClient) (*confluent.Message, error) {
+		return &confluent.Message{
+			TopicPartition: confluent.TopicPartition{Topic: &topic, Partition: 0, Offset: confluent.Offset(offset)},
+			Value:          []byte("{}"),
+		}, nil
+	}
+}
+
+func fail(err error) func(c *fakeClient) (*confluent.Message, error) {
+	return func(c *fakeClient) (*confluent.Message, error) { return nil, err }
+}
+
+func revoke(lost bool) func(c *fakeClient) (*confluent.Message, error) {
+	return func(c *fakeClient) (*confluent.Message, error) {
+		c.lost = lost
+		if err := c.rebalance(nil, confluent.RevokedPartitions{}); err != nil {
+			return nil, err
+		}
+		return nil, timeoutError()
+	}
+}
+
+func runConsumer(t *testing.T, client *fakeClient, interval time.Duration, batch int, handler messaging.MessageHandler) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client.done = cancel
+
+	consumer := kafka.NewTestConsumer(client, interval, batch)
+	return consumer.Run(ctx, []string{"events"}, handler)
+}
+
+func accept(ctx context.Context, msg *messaging.Message) error { return nil }
+
+func TestKafkaConsumer_ReturnsFatalErrors(t *testing.T) {
+	client := &fakeClient{steps: []func(c *fakeClient) (*confluent.Message, error){
+		fail(confluent.NewError(confluent.ErrFatal, "fenced", true)),
+		message(1),
+	}}
+
+	err := runConsumer(t, client, time.Hour, 100, accept)
+
+	var kerr confluent.Error
+	require.ErrorAs(t, err, &kerr)
+	assert.True(t, kerr.IsFatal())
+	assert.Empty(t, client.stored)
+}
+
+func TestKafkaConsumer_SkipsTimeoutsAndTransientErrors(t *testing.T) {
+	client := &fakeClient{steps: []func(c *fakeClient) (*confluent.Message, error){
+		fail(timeoutError()),
+		message(1),
+		fail(confluent.NewError(confluent.ErrTransport, "broker down", false)),
+		message(2),
+	}}
+
+	var handled []int64
+	require.NoError(t, runConsumer(t, client, time.Hour, 100, func(ctx context.Context, msg *messaging.Message) error {
+		handled = append(handled, msg.Offset)
+		return nil
+	}))
+
+	assert.Equal(t, []int64{1, 2}, handled)
+	assert.Equal(t, []int64{1, 2}, client.stored)
+	assert.Equal(t, []int{2}, client.commits, "committed once on shutdown")
+}
+
+func TestKafkaConsumer_DoesNotStoreFailedMessages(t *testing.T) {
+	client := &fakeClient{steps: []func(c *fakeClient) (*confluent.Message, error){
+		message(1),
+		message(2),
+	}}
+
+	require.NoError(t, runConsumer(t, client, time.Hour, 100, func(ctx context.Context, msg *messaging.Message) error {
+		if msg.Offset == 1 {
+			return errors.New("handler failed")
+		}
+		return nil
+	}))
+
+	assert.Equal(t, []int64{2}, client.stored)
+}
+
+func TestKafkaConsumer_CommitsInBatches(t *testing.T) {
+	client := &fakeClient{steps: []func(c *fakeClient) (*confluent.Message, error){
+		message(1), message(2), message(3), message(4), message(5),
+	}}
+
+	require.NoError(t, runConsumer(t, client, time.Hour, 2, accept))
+
+	// After every second message, then the rest on shutdown.
+	assert.Equal(t, []int{2, 4, 5}, client.commits)
+}
+
+func TestKafkaConsumer_CommitsAfterInterval(t *testing.T) {
+	client := &fakeClient{steps: []func(c *fakeClient) (*confluent.Message, error){
+		message(1), message(2),
+	}}
+
+	require.NoError(t, runConsumer(t, client, 0, 100, accept))
+
+	assert.Equal(t, []int{1, 2}, client.commits)
+}
+
+func TestKafkaConsumer_CommitsOnRevokeUnlessAssignmentLost(t *testing.T) {
+	client := &fakeClient{steps: []func(c *fakeClient) (*confluent.Message, error){
+		message(1),
+		revoke(true),
+		message(2),
+		revoke(false),
+	}}
+
+	require.NoError(t, runConsumer(t, client, time.Hour, 100, accept))
+
+	// Nothing is committed when the assignment was lost; the offsets are
+	// committed on the next regular revoke.
+	assert.Equal(t, []int{2}, client.commits)
+}
@@ -0,0 +1,48 @@
+package kafka
+
+import (
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+var NewConfigMap = newConfigMap
+
+// ConsumerClient is consumerClient for fakes in tests.
+type ConsumerClient = consumerClient
+
+func NewTestConsumer(client ConsumerClient, commitInterval time.Duration, commitBatchSize int) *KafkaConsumer {
+	return &KafkaConsumer{
+		consumer:        client,
+		commitInterval:  commitInterval,
+		commitBatchSize: commitBatchSize,
+	}
+}
+
+type Watermarks = watermarks
+
+func NewWatermarks(low, high int64) Watermarks {
+	return watermarks{low: low, high: high}
+}
+
+var (
+	PlanReplay  = planReplay
+	InRange     = inRange
+	MarkRead    = markRead
+	DropReached = dropReached
+)
+
+var (
+	CreationProblems = creationProblems
+	LayoutProblems   = layoutProblems
+)
+
+func (s TopicSpec) Specification() kafka.TopicSpecification {
+	return s.specification()
+}
+
+var ComputePartitionLag = partitionLag
+
+func (m *LagMonitor) Update() {
+	m.update()
+}
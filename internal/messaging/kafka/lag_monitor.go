@@ -0,0 +1,182 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"service-info-aggregator/internal/metrics"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+const readinessLagCheck = "consumer-lag"
+
+type ReadinessReporter interface {
+	NotReady(name, reason string)
+	Ready(name string)
+}
+
+type PartitionLag struct {
+	Topic         string `json:"topic"`
+	Partition     int32  `json:"partition"`
+	Committed     int64  `json:"committed"`
+	HighWatermark int64  `json:"high_watermark"`
+	Lag           int64  `json:"lag"`
+}
+
+type LagSnapshot struct {
+	Partitions []PartitionLag `json:"partitions"`
+	TotalLag   int64          `json:"total_lag"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// LagMonitor periodically compares the committed offsets of the consumer's
+// assigned partitions with their high watermarks.
+type LagMonitor struct {
+	consumer  *KafkaConsumer
+	interval  time.Duration
+	maxLag    int64
+	readiness ReadinessReporter
+
+	mu       sync.RWMutex
+	snapshot LagSnapshot
+
+	// reported holds the topic/partition labels with gauges, so that gauges
+	// of partitions revoked by a rebalance can be removed.
+	reported map[[2]string]struct{}
+}
+
+func NewLagMonitor(consumer *KafkaConsumer, interval time.Duration, maxLag int64, readiness ReadinessReporter) *LagMonitor {
+	return &LagMonitor{
+		consumer:  consumer,
+		interval:  interval,
+		maxLag:    maxLag,
+		readiness: readiness,
+		reported:  make(map[[2]string]struct{}),
+	}
+}
+
+func (m *LagMonitor) Start(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.update()
+		}
+	}
+}
+
+func (m *LagMonitor) Snapshot() LagSnapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.snapshot
+}
+
+func (m *LagMonitor) update() {
+	snapshot := LagSnapshot{UpdatedAt: time.Now().UTC()}
+
+	partitions, err := m.consumer.lag(int(m.interval.Milliseconds()))
+	if err != nil {
+		slog.Warn("failed to compute consumer lag", "error", err)
+		snapshot.Error = err.Error()
+	}
+	snapshot.Partitions = partitions
+
+	current := make(map[[2]string]struct{}, len(partitions))
+	for _, p := range partitions {
+		snapshot.TotalLag += p.Lag
+
+		partition := strconv.Itoa(int(p.Partition))
+		current[[2]string{p.Topic, partition}] = struct{}{}
+		m.reported[[2]string{p.Topic, partition}] = struct{}{}
+		metrics.ConsumerLag.WithLabelValues(p.Topic, partition).Set(float64(p.Lag))
+		metrics.ConsumerCommittedOffset.WithLabelValues(p.Topic, partition).Set(float64(p.Committed))
+		metrics.ConsumerHighWatermark.WithLabelValues(p.Topic, partition).Set(float64(p.HighWatermark))
+	}
+	// A failed check may list only some of the assigned partitions.
+	if err == nil {
+		m.removeRevoked(current)
+	}
+
+	m.mu.Lock()
+	m.snapshot = snapshot
+	m.mu.Unlock()
+
+	if m.readiness == nil || m.maxLag <= 0 {
+		return
+	}
+	if snapshot.TotalLag > m.maxLag {
+		m.readiness.NotReady(readinessLagCheck, fmt.Sprintf("consumer lag %d exceeds %d", snapshot.TotalLag, m.maxLag))
+	} else {
+		m.readiness.Ready(readinessLagCheck)
+	}
+}
+
+func (m *LagMonitor) removeRevoked(current map[[2]string]struct{}) {
+	for labels := range m.reported {
+		if _, ok := current[labels]; ok {
+			continue
+		}
+		metrics.ConsumerLag.DeleteLabelValues(labels[0], labels[1])
+		metrics.ConsumerCommittedOffset.DeleteLabelValues(labels[0], labels[1])
+		metrics.ConsumerHighWatermark.DeleteLabelValues(labels[0], labels[1])
+		delete(m.reported, labels)
+	}
+}
+
+func (c *KafkaConsumer) lag(timeoutMs int) ([]PartitionLag, error) {
+	assigned, err := c.consumer.Assignment()
+	if err != nil {
+		return nil, err
+	}
+	if len(assigned) == 0 {
+		return nil, nil
+	}
+
+	committed, err := c.consumer.Committed(assigned, timeoutMs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]PartitionLag, 0, len(committed))
+	for _, tp := range committed {
+		if tp.Topic == nil {
+			continue
+		}
+		low, high, err := c.consumer.QueryWatermarkOffsets(*tp.Topic, tp.Partition, timeoutMs)
+		if err != nil {
+			return result, fmt.Errorf("could not query offsets of %s [%d]: %w", *tp.Topic, tp.Partition, err)
+		}
+		result = append(result, partitionLag(*tp.Topic, tp.Partition, tp.Offset, low, high))
+	}
+	return result, nil
+}
+
+// partitionLag counts the messages between the committed offset and the high
+// watermark.
+func partitionLag(topic string, partition int32, committed kafka.Offset, low, high int64) PartitionLag {
+	// Nothing committed yet: with auto.offset.reset=earliest the group
+	// starts from the low watermark, as it does when retention or
+	// compaction removed the committed offset.
+	position := int64(committed)
+	if committed == kafka.OffsetInvalid || position < low {
+		position = low
+	}
+
+	return PartitionLag{
+		Topic:         topic,
+		Partition:     partition,
+		Committed:     int64(committed),
+		HighWatermark: high,
+		Lag:           max(high-position, 0),
+	}
+}
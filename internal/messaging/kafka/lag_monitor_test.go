@@ -0,0 +1,110 @@
+package kafka_test
+
+import (
+	"testing"
+	"time"
+
+	"service-info-aggregator/internal/messaging/kafka"
+	"service-info-aggregator/internal/metrics"
+
+	confluent "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lagClient reports committed offsets and watermarks of its assignment.
+type lagClient struct {
+	fakeClient
+	assigned   []confluent.TopicPartition
+	committed  map[int32]confluent.Offset
+	watermarks map[int32][2]int64
+}
+
+func (c *lagClient) Assignment() ([]confluent.TopicPartition, error) { return c.assigned, nil }
+
+func (c *lagClient) Committed(partitions []confluent.TopicPartition, timeoutMs int) ([]confluent.TopicPartition, error) {
+	result := make([]confluent.TopicPartition, 0, len(partitions))
+	for _, tp := range partitions {
+		tp.Offset = c.committed[tp.Partition]
+		result = append(result, tp)
+	}
+	return result, nil
+}
+
+func (c *lagClient) QueryWatermarkOffsets(topic string, partition int32, timeoutMs int) (int64, int64, error) {
+	w := c.watermarks[partition]
+	return w[0], w[1], nil
+}
+
+type readinessRecorder struct {
+	pending map[string]string
+}
+
+func (r *readinessRecorder) NotReady(name, reason string) { r.pending[name] = reason }
+
+func (r *readinessRecorder) Ready(name string) { delete(r.pending, name) }
+
+func TestPartitionLag(t *testing.T) {
+	tests := []struct {
+		name      string
+		committed confluent.Offset
+		low, high int64
+		lag       int64
+	}{
+		{"behind the high watermark", 70, 0, 100, 30},
+		{"caught up", 100, 0, 100, 0},
+		{"nothing committed", confluent.OffsetInvalid, 20, 100, 80},
+		{"committed offset removed by retention", 5, 20, 100, 80},
+		{"empty partition", confluent.OffsetInvalid, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lag := kafka.ComputePartitionLag("events", 2, tt.committed, tt.low, tt.high)
+
+			assert.Equal(t, tt.lag, lag.Lag)
+			assert.Equal(t, int64(tt.committed), lag.Committed)
+			assert.Equal(t, tt.high, lag.HighWatermark)
+			assert.Equal(t, "events", lag.Topic)
+			assert.Equal(t, int32(2), lag.Partition)
+		})
+	}
+}
+
+func TestLagMonitor_SnapshotMetricsAndReadiness(t *testing.T) {
+	topic := "lag-monitor-test"
+	client := &lagClient{
+		assigned: []confluent.TopicPartition{
+			{Topic: &topic, Partition: 0},
+			{Topic: &topic, Partition: 1},
+		},
+		committed:  map[int32]confluent.Offset{0: 90, 1: confluent.OffsetInvalid},
+		watermarks: map[int32][2]int64{0: {0, 100}, 1: {10, 50}},
+	}
+	readiness := &readinessRecorder{pending: map[string]string{}}
+	monitor := kafka.NewLagMonitor(kafka.NewTestConsumer(client, time.Hour, 100), time.Second, 40, readiness)
+
+	monitor.Update()
+
+	snapshot := monitor.Snapshot()
+	assert.Empty(t, snapshot.Error)
+	assert.Equal(t, int64(50), snapshot.TotalLag)
+	require.Len(t, snapshot.Partitions, 2)
+	assert.Equal(t, int64(10), snapshot.Partitions[0].Lag)
+	assert.Equal(t, int64(40), snapshot.Partitions[1].Lag)
+
+	assert.Equal(t, 10.0, testutil.ToFloat64(metrics.ConsumerLag.WithLabelValues(topic, "0")))
+	assert.Equal(t, 90.0, testutil.ToFloat64(metrics.ConsumerCommittedOffset.WithLabelValues(topic, "0")))
+	assert.Equal(t, 50.0, testutil.ToFloat64(metrics.ConsumerHighWatermark.WithLabelValues(topic, "1")))
+	assert.Equal(t, map[string]string{"consumer-lag": "consumer lag 50 exceeds 40"}, readiness.pending)
+
+	// Partition 1 is revoked and partition 0 catches up.
+	client.assigned = client.assigned[:1]
+	client.committed[0] = 100
+	monitor.Update()
+
+	assert.Equal(t, int64(0), monitor.Snapshot().TotalLag)
+	assert.Empty(t, readiness.pending)
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.ConsumerLag.WithLabelValues(topic, "0")))
+	assert.False(t, metrics.ConsumerLag.DeleteLabelValues(topic, "1"), "gauge of the revoked partition is removed")
+}
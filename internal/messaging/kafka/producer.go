@@ -2,7 +2,9 @@ package kafka
 
 import (
 	"context"
-	"strings"
+
+	"service-info-aggregator/internal/config"
+	"service-info-aggregator/internal/messaging"
 
 	ckafka "github.com/confluentinc/confluent-kafka-go/v2/kafka"
 )
@@ -11,26 +13,38 @@ type KafkaProducer struct {
 	producer *ckafka.Producer
 }
 
-func NewKafkaProducer(brokers []string, clientID string) (*KafkaProducer, error) {
-	p, err := ckafka.NewProducer(&ckafka.ConfigMap{
-		"bootstrap.servers": strings.Join(brokers, ","),
-		"client.id":         clientID,
-		"acks":              "all",
+func NewKafkaProducer(cfg *config.KafkaConfig) (*KafkaProducer, error) {
+	cm, err := newConfigMap(cfg, cfg.ClientID+"-producer", ckafka.ConfigMap{
+		"acks":               "all",
+		"enable.idempotence": true,
 	})
 	if err != nil {
 		return nil, err
 	}
+
+	p, err := ckafka.NewProducer(cm)
+	if err != nil {
+		return nil, err
+	}
 	return &KafkaProducer{producer: p}, nil
 }
 
-func (p *KafkaProducer) Publish(ctx context.Context, topic, key string, payload []byte) error {
+func (p *KafkaProducer) Publish(ctx context.Context, msg *messaging.Message) error {
+	messaging.InjectMetadata(ctx, msg)
+
+	headers := make([]ckafka.Header, 0, len(msg.Headers))
+	for _, h := range msg.Headers {
+		headers = append(headers, ckafka.Header{Key: h.Key, Value: h.Value})
+	}
+
 	return p.producer.Produce(&ckafka.Message{
 		TopicPartition: ckafka.TopicPartition{
-			Topic:     &topic,
+			Topic:     &msg.Topic,
 			Partition: ckafka.PartitionAny,
 		},
-		Key:   []byte(key),
-		Value: payload,
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
 	}, nil)
 }
 
@@ -0,0 +1,223 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"service-info-aggregator/internal/config"
+	"service-info-aggregator/internal/messaging"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+const metadataTimeoutMs = 10000
+
+// ReplayOptions selects where every partition starts: FromTimestamp if set,
+// otherwise FromOffset, falling back to the earliest retained offset.
+type ReplayOptions struct {
+	Topic         string
+	FromOffset    int64
+	FromTimestamp time.Time
+}
+
+// Replayer reads a topic from a fixed starting point up to the high
+// watermarks observed when it starts, without joining a consumer group or
+// committing offsets.
+type Replayer struct {
+	consumer *kafka.Consumer
+}
+
+func NewReplayer(cfg *config.KafkaConfig, clientID string) (*Replayer, error) {
+	cm, err := newConfigMap(cfg, clientID, kafka.ConfigMap{
+		"group.id":           clientID,
+		"enable.auto.commit": false,
+		"auto.offset.reset":  "earliest",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := kafka.NewConsumer(cm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Replayer{consumer: c}, nil
+}
+
+func (r *Replayer) Run(ctx context.Context, opts ReplayOptions, handler messaging.MessageHandler) (int, error) {
+	assignment, ends, err := r.startOffsets(opts)
+	if err != nil {
+		return 0, err
+	}
+	if len(assignment) == 0 {
+		return 0, nil
+	}
+
+	if err := r.consumer.Assign(assignment); err != nil {
+		return 0, fmt.Errorf("could not assign partitions of %s: %w", opts.Topic, err)
+	}
+	defer r.consumer.Unassign()
+
+	read := 0
+	for len(ends) > 0 {
+		if ctx.Err() != nil {
+			return read, ctx.Err()
+		}
+
+		msg, err := r.consumer.ReadMessage(time.Second)
+		if err != nil {
+			var kerr kafka.Error
+			if errors.As(err, &kerr) && kerr.IsTimeout() {
+				// Compaction can leave the last offsets before the watermark
+				// empty, so check the positions instead of waiting forever.
+				r.dropFinished(assignment, ends)
+				continue
+			}
+			return read, err
+		}
+
+		partition, offset := msg.TopicPartition.Partition, int64(msg.TopicPartition.Offset)
+		if !inRange(ends, partition, offset) {
+			continue
+		}
+
+		read++
+		if err := handler(ctx, fromKafkaMessage(msg)); err != nil {
+			return read, fmt.Errorf("replay of %s [%d] at offset %d failed: %w",
+				opts.Topic, partition, offset, err)
+		}
+
+		markRead(ends, partition, offset)
+	}
+
+	return read, nil
+}
+
+func (r *Replayer) dropFinished(assignment []kafka.TopicPartition, ends map[int32]int64) {
+	positions, err := r.consumer.Position(assignment)
+	if err != nil {
+		return
+	}
+	dropReached(ends, positions)
+}
+
+// inRange reports whether offset lies before the end of its partition's
+// replay.
+func inRange(ends map[int32]int64, partition int32, offset int64) bool {
+	end, ok := ends[partition]
+	return ok && offset < end
+}
+
+// markRead finishes the partition once the offset before its end was read.
+func markRead(ends map[int32]int64, partition int32, offset int64) {
+	if offset+1 >= ends[partition] {
+		delete(ends, partition)
+	}
+}
+
+// dropReached finishes the partitions whose position reached their end.
+func dropReached(ends map[int32]int64, positions []kafka.TopicPartition) {
+	for _, tp := range positions {
+		if tp.Offset >= 0 && int64(tp.Offset) >= ends[tp.Partition] {
+			delete(ends, tp.Partition)
+		}
+	}
+}
+
+func (r *Replayer) startOffsets(opts ReplayOptions) ([]kafka.TopicPartition, map[int32]int64, error) {
+	md, err := r.consumer.GetMetadata(&opts.Topic, false, metadataTimeoutMs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not load metadata for %s: %w", opts.Topic, err)
+	}
+	topicMd, ok := md.Topics[opts.Topic]
+	if !ok || topicMd.Error.Code() == kafka.ErrUnknownTopicOrPart {
+		return nil, nil, fmt.Errorf("topic %s does not exist", opts.Topic)
+	}
+
+	marks := make(map[int32]watermarks)
+	var byTime []kafka.TopicPartition
+	for _, p := range topicMd.Partitions {
+		low, high, err := r.consumer.QueryWatermarkOffsets(opts.Topic, p.ID, metadataTimeoutMs)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not query offsets of %s [%d]: %w", opts.Topic, p.ID, err)
+		}
+		marks[p.ID] = watermarks{low: low, high: high}
+
+		if !opts.FromTimestamp.IsZero() && high > low {
+			byTime = append(byTime, kafka.TopicPartition{
+				Topic:     &opts.Topic,
+				Partition: p.ID,
+				Offset:    kafka.Offset(opts.FromTimestamp.UnixMilli()),
+			})
+		}
+	}
+
+	var resolved []kafka.TopicPartition
+	if len(byTime) > 0 {
+		resolved, err = r.consumer.OffsetsForTimes(byTime, metadataTimeoutMs)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not resolve offsets for %s: %w", opts.FromTimestamp, err)
+		}
+	}
+
+	assignment, ends := planReplay(opts, marks, resolved)
+	return assignment, ends, nil
+}
+
+type watermarks struct {
+	low, high int64
+}
+
+// planReplay picks the start of every partition holding messages, and the
+// high watermark it is read up to. resolved holds the offsets found for
+// FromTimestamp; a partition without messages since then is skipped.
+func planReplay(opts ReplayOptions, marks map[int32]watermarks, resolved []kafka.TopicPartition) ([]kafka.TopicPartition, map[int32]int64) {
+	starts := make(map[int32]int64)
+	ends := make(map[int32]int64)
+
+	for partition, m := range marks {
+		if m.high <= m.low {
+			continue
+		}
+		ends[partition] = m.high
+
+		switch {
+		case !opts.FromTimestamp.IsZero():
+			starts[partition] = m.high
+		case opts.FromOffset > m.low:
+			starts[partition] = opts.FromOffset
+		default:
+			starts[partition] = m.low
+		}
+	}
+
+	for _, tp := range resolved {
+		if _, ok := ends[tp.Partition]; ok && tp.Offset >= 0 {
+			starts[tp.Partition] = int64(tp.Offset)
+		}
+	}
+
+	assignment := make([]kafka.TopicPartition, 0, len(starts))
+	for partition, start := range starts {
+		if start >= ends[partition] {
+			delete(ends, partition)
+			continue
+		}
+		assignment = append(assignment, kafka.TopicPartition{
+			Topic:     &opts.Topic,
+			Partition: partition,
+			Offset:    kafka.Offset(start),
+		})
+	}
+	slices.SortFunc(assignment, func(a, b kafka.TopicPartition) int { return int(a.Partition - b.Partition) })
+
+	return assignment, ends
+}
+
+func (r *Replayer) Close() {
+	r.consumer.Close()
+}
@@ -0,0 +1,96 @@
+package kafka_test
+
+import (
+	"testing"
+	"time"
+
+	"service-info-aggregator/internal/messaging/kafka"
+
+	confluent "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+)
+
+// starts maps the planned assignment to partition -> start offset.
+func starts(assignment []confluent.TopicPartition) map[int32]int64 {
+	m := make(map[int32]int64, len(assignment))
+	for _, tp := range assignment {
+		m[tp.Partition] = int64(tp.Offset)
+	}
+	return m
+}
+
+func TestPlanReplay_FromOffset(t *testing.T) {
+	marks := map[int32]kafka.Watermarks{
+		0: kafka.NewWatermarks(0, 100),
+		1: kafka.NewWatermarks(60, 80), // compacted below the offset asked for
+		2: kafka.NewWatermarks(10, 10), // empty
+		3: kafka.NewWatermarks(0, 30),  // ends before the offset
+	}
+
+	assignment, ends := kafka.PlanReplay(kafka.ReplayOptions{Topic: "events", FromOffset: 50}, marks, nil)
+
+	assert.Equal(t, map[int32]int64{0: 50, 1: 60}, starts(assignment))
+	assert.Equal(t, map[int32]int64{0: 100, 1: 80}, ends)
+	for _, tp := range assignment {
+		assert.Equal(t, "events", *tp.Topic)
+	}
+}
+
+func TestPlanReplay_FromEarliest(t *testing.T) {
+	marks := map[int32]kafka.Watermarks{
+		0: kafka.NewWatermarks(5, 100),
+		1: kafka.NewWatermarks(0, 3),
+	}
+
+	assignment, ends := kafka.PlanReplay(kafka.ReplayOptions{Topic: "events", FromOffset: -1}, marks, nil)
+
+	assert.Equal(t, map[int32]int64{0: 5, 1: 0}, starts(assignment))
+	assert.Equal(t, map[int32]int64{0: 100, 1: 3}, ends)
+}
+
+func TestPlanReplay_FromTimestamp(t *testing.T) {
+	topic := "events"
+	marks := map[int32]kafka.Watermarks{
+		0: kafka.NewWatermarks(0, 100),
+		1: kafka.NewWatermarks(0, 50),
+		2: kafka.NewWatermarks(0, 0),
+	}
+	// Partition 1 has no message since the timestamp.
+	resolved := []confluent.TopicPartition{
+		{Topic: &topic, Partition: 0, Offset: 42},
+		{Topic: &topic, Partition: 1, Offset: confluent.OffsetEnd},
+	}
+
+	assignment, ends := kafka.PlanReplay(kafka.ReplayOptions{Topic: topic, FromTimestamp: time.Now()}, marks, resolved)
+
+	assert.Equal(t, map[int32]int64{0: 42}, starts(assignment))
+	assert.Equal(t, map[int32]int64{0: 100}, ends)
+}
+
+func TestReplay_StopsAtHighWatermark(t *testing.T) {
+	ends := map[int32]int64{0: 3, 1: 10}
+
+	assert.True(t, kafka.InRange(ends, 0, 1))
+	assert.False(t, kafka.InRange(ends, 0, 3), "produced after the replay started")
+	assert.False(t, kafka.InRange(ends, 2, 0), "not assigned")
+
+	kafka.MarkRead(ends, 0, 1)
+	assert.Contains(t, ends, int32(0))
+	kafka.MarkRead(ends, 0, 2)
+	assert.NotContains(t, ends, int32(0))
+
+	// Compaction left the offsets before the watermark empty.
+	kafka.DropReached(ends, []confluent.TopicPartition{
+		{Partition: 1, Offset: 10},
+	})
+	assert.Empty(t, ends)
+}
+
+func TestReplay_DropReachedIgnoresUnknownPositions(t *testing.T) {
+	ends := map[int32]int64{0: 3}
+
+	kafka.DropReached(ends, []confluent.TopicPartition{{Partition: 0, Offset: confluent.OffsetInvalid}})
+	kafka.DropReached(ends, []confluent.TopicPartition{{Partition: 0, Offset: 2}})
+
+	assert.Equal(t, map[int32]int64{0: 3}, ends)
+}
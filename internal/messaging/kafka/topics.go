@@ -0,0 +1,172 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"service-info-aggregator/internal/config"
+	"service-info-aggregator/internal/messaging"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+type TopicSpec struct {
+	Name              string
+	Partitions        int
+	ReplicationFactor int
+	Config            map[string]string
+}
+
+// TopicSpecs lists the topics the service uses: the compacted state topic,
+// the DLQ, every routed events topic and the retry topics.
+func TopicSpecs(cfg *config.KafkaConfig) []TopicSpec {
+	topic := func(name string, policy string) TopicSpec {
+		return TopicSpec{
+			Name:              name,
+			Partitions:        cfg.TopicPartitions,
+			ReplicationFactor: cfg.ReplicationFactor,
+			Config:            map[string]string{"cleanup.policy": policy},
+		}
+	}
+
+	specs := []TopicSpec{
+		topic(cfg.StateTopic, "compact"),
+		topic(cfg.DLQTopic, "delete"),
+	}
+	for _, name := range messaging.NewTopicRoutes(cfg.Topic, cfg.TopicRoutes).Topics() {
+		specs = append(specs, topic(name, "delete"))
+	}
+	for _, name := range cfg.RetryTopics {
+		specs = append(specs, topic(name, "delete"))
+	}
+	return specs
+}
+
+func (s TopicSpec) specification() kafka.TopicSpecification {
+	return kafka.TopicSpecification{
+		Topic:             s.Name,
+		NumPartitions:     s.Partitions,
+		ReplicationFactor: s.ReplicationFactor,
+		Config:            s.Config,
+	}
+}
+
+type TopicProvisioner struct {
+	admin   *kafka.AdminClient
+	timeout time.Duration
+}
+
+func NewTopicProvisioner(cfg *config.KafkaConfig) (*TopicProvisioner, error) {
+	cm, err := newConfigMap(cfg, cfg.ClientID+"-admin", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	admin, err := kafka.NewAdminClient(cm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TopicProvisioner{admin: admin, timeout: cfg.AdminTimeout}, nil
+}
+
+// Ensure creates the missing topics when create is set and checks that the
+// existing ones match their spec. Every mismatch is reported in one error.
+func (p *TopicProvisioner) Ensure(ctx context.Context, specs []TopicSpec, create bool) error {
+	md, err := p.admin.GetMetadata(nil, true, int(p.timeout.Milliseconds()))
+	if err != nil {
+		return fmt.Errorf("could not load cluster metadata: %w", err)
+	}
+
+	var missing []kafka.TopicSpecification
+	var problems []string
+
+	for _, spec := range specs {
+		topicMd, ok := md.Topics[spec.Name]
+		if !ok || topicMd.Error.Code() == kafka.ErrUnknownTopicOrPart {
+			if !create {
+				problems = append(problems, fmt.Sprintf("topic %s does not exist", spec.Name))
+				continue
+			}
+			missing = append(missing, spec.specification())
+			continue
+		}
+
+		problems = append(problems, p.verify(ctx, spec, topicMd)...)
+	}
+
+	if len(missing) > 0 {
+		results, err := p.admin.CreateTopics(ctx, missing, kafka.SetAdminOperationTimeout(p.timeout))
+		if err != nil {
+			return fmt.Errorf("could not create topics: %w", err)
+		}
+		problems = append(problems, creationProblems(results)...)
+	}
+
+	if len(problems) > 0 {
+		return errors.New("kafka topics do not match the expected layout:\n  " + strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// creationProblems reports the topics that could not be created. Topics
+// created concurrently, e.g. by another replica, are fine.
+func creationProblems(results []kafka.TopicResult) []string {
+	var problems []string
+	for _, res := range results {
+		switch res.Error.Code() {
+		case kafka.ErrNoError:
+			slog.Info("kafka topic created", "topic", res.Topic)
+		case kafka.ErrTopicAlreadyExists:
+		default:
+			problems = append(problems, fmt.Sprintf("topic %s could not be created: %v", res.Topic, res.Error))
+		}
+	}
+	return problems
+}
+
+func layoutProblems(spec TopicSpec, md kafka.TopicMetadata) []string {
+	var problems []string
+
+	if len(md.Partitions) != spec.Partitions {
+		problems = append(problems, fmt.Sprintf("topic %s has %d partitions, expected %d",
+			spec.Name, len(md.Partitions), spec.Partitions))
+	}
+	if len(md.Partitions) > 0 && len(md.Partitions[0].Replicas) != spec.ReplicationFactor {
+		problems = append(problems, fmt.Sprintf("topic %s has replication factor %d, expected %d",
+			spec.Name, len(md.Partitions[0].Replicas), spec.ReplicationFactor))
+	}
+	return problems
+}
+
+func (p *TopicProvisioner) verify(ctx context.Context, spec TopicSpec, md kafka.TopicMetadata) []string {
+	problems := layoutProblems(spec, md)
+
+	if len(spec.Config) == 0 {
+		return problems
+	}
+
+	results, err := p.admin.DescribeConfigs(ctx,
+		[]kafka.ConfigResource{{Type: kafka.ResourceTopic, Name: spec.Name}},
+		kafka.SetAdminRequestTimeout(p.timeout))
+	if err != nil || len(results) == 0 {
+		return append(problems, fmt.Sprintf("could not describe config of topic %s: %v", spec.Name, err))
+	}
+
+	for key, expected := range spec.Config {
+		entry, ok := results[0].Config[key]
+		if !ok || entry.Value != expected {
+			problems = append(problems, fmt.Sprintf("topic %s has %s=%q, expected %q",
+				spec.Name, key, entry.Value, expected))
+		}
+	}
+	return problems
+}
+
+func (p *TopicProvisioner) Close() {
+	p.admin.Close()
+}
@@ -0,0 +1,84 @@
+package kafka_test
+
+import (
+	"testing"
+
+	"service-info-aggregator/internal/config"
+	"service-info-aggregator/internal/messaging/kafka"
+
+	confluent "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopicSpecs(t *testing.T) {
+	specs := kafka.TopicSpecs(&config.KafkaConfig{
+		Topic:             "events",
+		TopicRoutes:       map[string]string{"weather": "events.weather"},
+		StateTopic:        "state",
+		DLQTopic:          "events.dlq",
+		RetryTopics:       []string{"events.retry.1m"},
+		TopicPartitions:   6,
+		ReplicationFactor: 3,
+	})
+
+	policies := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		assert.Equal(t, 6, spec.Partitions, spec.Name)
+		assert.Equal(t, 3, spec.ReplicationFactor, spec.Name)
+		policies[spec.Name] = spec.Config["cleanup.policy"]
+	}
+	assert.Equal(t, map[string]string{
+		"state":           "compact",
+		"events.dlq":      "delete",
+		"events":          "delete",
+		"events.weather":  "delete",
+		"events.retry.1m": "delete",
+	}, policies)
+}
+
+func TestTopicSpec_Specification(t *testing.T) {
+	spec := kafka.TopicSpec{
+		Name:              "state",
+		Partitions:        6,
+		ReplicationFactor: 3,
+		Config:            map[string]string{"cleanup.policy": "compact"},
+	}
+
+	assert.Equal(t, confluent.TopicSpecification{
+		Topic:             "state",
+		NumPartitions:     6,
+		ReplicationFactor: 3,
+		Config:            map[string]string{"cleanup.policy": "compact"},
+	}, spec.Specification())
+}
+
+func TestCreationProblems_ToleratesExistingTopics(t *testing.T) {
+	problems := kafka.CreationProblems([]confluent.TopicResult{
+		{Topic: "created", Error: confluent.NewError(confluent.ErrNoError, "", false)},
+		{Topic: "raced", Error: confluent.NewError(confluent.ErrTopicAlreadyExists, "already exists", false)},
+		{Topic: "denied", Error: confluent.NewError(confluent.ErrTopicAuthorizationFailed, "not authorized", false)},
+		{Topic: "invalid", Error: confluent.NewError(confluent.ErrInvalidReplicationFactor, "too few brokers", false)},
+	})
+
+	require.Len(t, problems, 2)
+	assert.Contains(t, problems[0], "topic denied could not be created")
+	assert.Contains(t, problems[1], "topic invalid could not be created")
+}
+
+func TestLayoutProblems(t *testing.T) {
+	spec := kafka.TopicSpec{Name: "events", Partitions: 3, ReplicationFactor: 3}
+	partition := confluent.PartitionMetadata{Replicas: []int32{1, 2, 3}}
+
+	assert.Empty(t, kafka.LayoutProblems(spec, confluent.TopicMetadata{
+		Partitions: []confluent.PartitionMetadata{partition, partition, partition},
+	}))
+
+	problems := kafka.LayoutProblems(spec, confluent.TopicMetadata{
+		Partitions: []confluent.PartitionMetadata{{Replicas: []int32{1}}},
+	})
+	assert.Equal(t, []string{
+		"topic events has 1 partitions, expected 3",
+		"topic events has replication factor 1, expected 3",
+	}, problems)
+}
@@ -0,0 +1,196 @@
+package memory
+
+import (
+	"context"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"service-info-aggregator/internal/messaging"
+)
+
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+type group struct {
+	members    map[*Subscriber][]string
+	committed  map[topicPartition]int64
+	generation int
+}
+
+// Broker is an in-process message broker with Kafka-like semantics: topics
+// are split into partitions by key, and every consumer group gets each
+// message once, with partitions spread across the group's members.
+type Broker struct {
+	mu         sync.Mutex
+	partitions int
+	topics     map[string][][]*messaging.Message
+	groups     map[string]*group
+	nextMember int
+	roundRobin int
+	changed    chan struct{}
+}
+
+func NewBroker(partitions int) *Broker {
+	if partitions < 1 {
+		partitions = 1
+	}
+	return &Broker{
+		partitions: partitions,
+		topics:     make(map[string][][]*messaging.Message),
+		groups:     make(map[string]*group),
+		changed:    make(chan struct{}),
+	}
+}
+
+func (b *Broker) Publish(ctx context.Context, msg *messaging.Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	partitions := b.topicLocked(msg.Topic)
+
+	var p int
+	if len(msg.Key) == 0 {
+		p = b.roundRobin % b.partitions
+		b.roundRobin++
+	} else {
+		h := fnv.New32a()
+		h.Write(msg.Key)
+		p = int(h.Sum32() % uint32(b.partitions))
+	}
+
+	stored := *msg
+	stored.Partition = int32(p)
+	stored.Offset = int64(len(partitions[p]))
+	stored.Timestamp = time.Now().UTC()
+	partitions[p] = append(partitions[p], &stored)
+
+	b.notifyLocked()
+	return nil
+}
+
+func (b *Broker) Close() {}
+
+func (b *Broker) NewSubscriber(groupID string) *Subscriber {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextMember++
+	return &Subscriber{
+		broker:  b,
+		groupID: groupID,
+		id:      b.nextMember,
+	}
+}
+
+func (b *Broker) topicLocked(name string) [][]*messaging.Message {
+	partitions, ok := b.topics[name]
+	if !ok {
+		partitions = make([][]*messaging.Message, b.partitions)
+		b.topics[name] = partitions
+	}
+	return partitions
+}
+
+func (b *Broker) notifyLocked() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+func (b *Broker) join(s *Subscriber, topics []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	g, ok := b.groups[s.groupID]
+	if !ok {
+		g = &group{
+			members:   make(map[*Subscriber][]string),
+			committed: make(map[topicPartition]int64),
+		}
+		b.groups[s.groupID] = g
+	}
+	for _, t := range topics {
+		b.topicLocked(t)
+	}
+	g.members[s] = topics
+	g.generation++
+	b.notifyLocked()
+}
+
+func (b *Broker) leave(s *Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if g, ok := b.groups[s.groupID]; ok {
+		delete(g.members, s)
+		g.generation++
+		b.notifyLocked()
+	}
+}
+
+// assignmentLocked spreads the partitions of every topic across the group
+// members subscribed to it, ordered by member id so all members agree.
+func (b *Broker) assignmentLocked(g *group, s *Subscriber) []topicPartition {
+	var assigned []topicPartition
+	for _, topic := range g.members[s] {
+		var members []*Subscriber
+		for m, topics := range g.members {
+			for _, t := range topics {
+				if t == topic {
+					members = append(members, m)
+					break
+				}
+			}
+		}
+		sort.Slice(members, func(i, j int) bool { return members[i].id < members[j].id })
+
+		for p := 0; p < b.partitions; p++ {
+			if members[p%len(members)] == s {
+				assigned = append(assigned, topicPartition{topic: topic, partition: int32(p)})
+			}
+		}
+	}
+	return assigned
+}
+
+// next returns the first pending message across the subscriber's partitions
+// or a channel that is closed when new messages or members arrive.
+func (b *Broker) next(s *Subscriber, positions map[topicPartition]int64, generation *int) (*messaging.Message, <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	g := b.groups[s.groupID]
+	if g.generation != *generation {
+		for tp := range positions {
+			delete(positions, tp)
+		}
+		*generation = g.generation
+	}
+
+	for _, tp := range b.assignmentLocked(g, s) {
+		pos, ok := positions[tp]
+		if !ok {
+			pos = g.committed[tp]
+		}
+		log := b.topics[tp.topic][tp.partition]
+		if pos < int64(len(log)) {
+			positions[tp] = pos + 1
+			return log[pos], nil
+		}
+		positions[tp] = pos
+	}
+	return nil, b.changed
+}
+
+func (b *Broker) commit(s *Subscriber, msg *messaging.Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	tp := topicPartition{topic: msg.Topic, partition: msg.Partition}
+	if g, ok := b.groups[s.groupID]; ok && g.committed[tp] <= msg.Offset {
+		g.committed[tp] = msg.Offset + 1
+	}
+}
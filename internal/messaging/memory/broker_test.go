@@ -0,0 +1,149 @@
+package memory_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"service-info-aggregator/internal/messaging"
+	"service-info-aggregator/internal/messaging/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type collector struct {
+	mu       sync.Mutex
+	messages []*messaging.Message
+}
+
+func (c *collector) handle(ctx context.Context, msg *messaging.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, msg)
+	return nil
+}
+
+func (c *collector) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.messages)
+}
+
+func publishN(t *testing.T, broker *memory.Broker, topic string, n int) {
+	for i := 0; i < n; i++ {
+		err := broker.Publish(context.Background(), &messaging.Message{
+			Topic: topic,
+			Key:   []byte(fmt.Sprintf("key-%d", i%5)),
+			Value: []byte(fmt.Sprintf("%d", i)),
+		})
+		require.NoError(t, err)
+	}
+}
+
+func runSubscriber(ctx context.Context, wg *sync.WaitGroup, sub *memory.Subscriber, topic string, c *collector) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = sub.Run(ctx, []string{topic}, c.handle)
+	}()
+}
+
+func TestBroker_DeliversToEveryGroup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	broker := memory.NewBroker(3)
+
+	first, second := &collector{}, &collector{}
+	runSubscriber(ctx, &wg, broker.NewSubscriber("a"), "events", first)
+	runSubscriber(ctx, &wg, broker.NewSubscriber("b"), "events", second)
+
+	publishN(t, broker, "events", 20)
+
+	require.Eventually(t, func() bool {
+		return first.count() == 20 && second.count() == 20
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	wg.Wait()
+}
+
+func TestBroker_SplitsPartitionsWithinGroup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	broker := memory.NewBroker(4)
+
+	first, second := &collector{}, &collector{}
+	runSubscriber(ctx, &wg, broker.NewSubscriber("g"), "events", first)
+	runSubscriber(ctx, &wg, broker.NewSubscriber("g"), "events", second)
+
+	// Let both members join before publishing so neither drains everything.
+	time.Sleep(20 * time.Millisecond)
+	publishN(t, broker, "events", 40)
+
+	require.Eventually(t, func() bool {
+		return first.count()+second.count() == 40
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	wg.Wait()
+
+	partitions := func(c *collector) map[int32]bool {
+		seen := make(map[int32]bool)
+		for _, m := range c.messages {
+			seen[m.Partition] = true
+		}
+		return seen
+	}
+	for p := range partitions(first) {
+		assert.False(t, partitions(second)[p], "partition %d consumed by both members", p)
+	}
+}
+
+func TestBroker_PreservesOrderPerKey(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	broker := memory.NewBroker(3)
+
+	c := &collector{}
+	runSubscriber(ctx, &wg, broker.NewSubscriber("g"), "events", c)
+	publishN(t, broker, "events", 50)
+
+	require.Eventually(t, func() bool { return c.count() == 50 }, time.Second, 5*time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	last := make(map[string]int64)
+	for _, m := range c.messages {
+		if prev, ok := last[string(m.Key)]; ok {
+			assert.Greater(t, m.Offset, prev)
+		}
+		last[string(m.Key)] = m.Offset
+	}
+}
+
+func TestBroker_ResumesFromCommittedOffset(t *testing.T) {
+	broker := memory.NewBroker(1)
+	publishN(t, broker, "events", 5)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	first := &collector{}
+	runSubscriber(ctx, &wg, broker.NewSubscriber("g"), "events", first)
+	require.Eventually(t, func() bool { return first.count() == 5 }, time.Second, 5*time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	publishN(t, broker, "events", 3)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	second := &collector{}
+	runSubscriber(ctx, &wg, broker.NewSubscriber("g"), "events", second)
+	require.Eventually(t, func() bool { return second.count() == 3 }, time.Second, 5*time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	assert.Equal(t, int64(5), second.messages[0].Offset)
+}
@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"log/slog"
+
+	"service-info-aggregator/internal/messaging"
+)
+
+type Subscriber struct {
+	broker  *Broker
+	groupID string
+	id      int
+}
+
+func (s *Subscriber) Run(ctx context.Context, topics []string, handler messaging.MessageHandler) error {
+	s.broker.join(s, topics)
+	defer s.broker.leave(s)
+
+	positions := make(map[topicPartition]int64)
+	generation := -1
+
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		msg, wait := s.broker.next(s, positions, &generation)
+		if msg == nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-wait:
+				continue
+			}
+		}
+
+		delivered := *msg
+		if err := handler(ctx, &delivered); err != nil {
+			slog.Error("message processing failed", "error", err)
+			continue
+		}
+
+		s.broker.commit(s, msg)
+	}
+}
+
+func (s *Subscriber) Close() {}
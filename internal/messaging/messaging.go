@@ -0,0 +1,54 @@
+package messaging
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+type Message struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   []Header
+	Timestamp time.Time
+}
+
+func (m *Message) Header(key string) (string, bool) {
+	for _, h := range m.Headers {
+		if strings.EqualFold(h.Key, key) {
+			return string(h.Value), true
+		}
+	}
+	return "", false
+}
+
+// SetHeader replaces every header with the given key, or appends one.
+func (m *Message) SetHeader(key, value string) {
+	headers := m.Headers[:0]
+	for _, h := range m.Headers {
+		if !strings.EqualFold(h.Key, key) {
+			headers = append(headers, h)
+		}
+	}
+	m.Headers = append(headers, Header{Key: key, Value: []byte(value)})
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, msg *Message) error
+	Close()
+}
+
+type MessageHandler func(ctx context.Context, msg *Message) error
+
+type Subscriber interface {
+	Run(ctx context.Context, topics []string, handler MessageHandler) error
+	Close()
+}
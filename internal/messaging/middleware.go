@@ -0,0 +1,85 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"service-info-aggregator/internal/metrics"
+	"service-info-aggregator/internal/model/events"
+)
+
+func LoggingMiddleware(next HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, event *events.CloudEvent) error {
+		started := time.Now()
+		err := next(ctx, event)
+		if err != nil {
+			slog.ErrorContext(ctx, "event handling failed",
+				"id", event.ID,
+				"type", event.Type,
+				"subject", event.Subject,
+				"duration", time.Since(started),
+				"error", err)
+			return err
+		}
+
+		slog.DebugContext(ctx, "event handled",
+			"id", event.ID,
+			"type", event.Type,
+			"subject", event.Subject,
+			"duration", time.Since(started))
+		return nil
+	}
+}
+
+func MetricsMiddleware(next HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, event *events.CloudEvent) error {
+		started := time.Now()
+		err := next(ctx, event)
+		metrics.EventHandleDuration.WithLabelValues(event.Type).Observe(time.Since(started).Seconds())
+
+		outcome := "success"
+		if err != nil {
+			outcome = "error"
+		}
+		metrics.EventsHandled.WithLabelValues(event.Type, outcome).Inc()
+		return err
+	}
+}
+
+func RecoveryMiddleware(next HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, event *events.CloudEvent) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				slog.ErrorContext(ctx, "event handler panicked", "id", event.ID, "type", event.Type, "panic", p, "stack", string(debug.Stack()))
+				err = fmt.Errorf("handler panicked on event %s: %v", event.ID, p)
+			}
+		}()
+		return next(ctx, event)
+	}
+}
+
+func TimeoutMiddleware(timeout time.Duration) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, event *events.CloudEvent) error {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return next(ctx, event)
+		}
+	}
+}
+
+func ValidationMiddleware(next HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, event *events.CloudEvent) error {
+		if err := event.Validate(); err != nil {
+			return err
+		}
+		if event.Subject == "" {
+			return errors.New("cloudevent subject is required")
+		}
+		return next(ctx, event)
+	}
+}
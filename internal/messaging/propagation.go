@@ -0,0 +1,48 @@
+package messaging
+
+import (
+	"context"
+	"strings"
+
+	"service-info-aggregator/internal/tracing"
+)
+
+const (
+	requestIDHeader    = "x-request-id"
+	defaultContentType = "application/octet-stream"
+)
+
+// InjectMetadata writes the trace context and request ID of ctx into the
+// message headers and makes sure a content type is set.
+func InjectMetadata(ctx context.Context, msg *Message) {
+	if sc, ok := tracing.SpanFromContext(ctx); ok {
+		msg.SetHeader(tracing.TraceparentHeader, sc.Child().Traceparent())
+		if sc.TraceState != "" {
+			msg.SetHeader(tracing.TracestateHeader, sc.TraceState)
+		}
+	}
+	if id, ok := tracing.RequestIDFromContext(ctx); ok {
+		msg.SetHeader(requestIDHeader, id)
+	}
+	if _, ok := msg.Header(contentTypeHeader); !ok {
+		msg.SetHeader(contentTypeHeader, defaultContentType)
+	}
+}
+
+// ExtractMetadata continues the trace and request ID found in the message
+// headers. Messages without a traceparent start a new trace.
+func ExtractMetadata(ctx context.Context, msg *Message) context.Context {
+	sc := tracing.NewSpanContext()
+	if raw, ok := msg.Header(tracing.TraceparentHeader); ok {
+		if parent, err := tracing.ParseTraceparent(raw); err == nil {
+			parent.TraceState, _ = msg.Header(tracing.TracestateHeader)
+			sc = parent.Child()
+		}
+	}
+	ctx = tracing.ContextWithSpan(ctx, sc)
+
+	if id, ok := msg.Header(requestIDHeader); ok && strings.TrimSpace(id) != "" {
+		ctx = tracing.ContextWithRequestID(ctx, id)
+	}
+	return ctx
+}
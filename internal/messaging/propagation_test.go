@@ -0,0 +1,47 @@
+package messaging_test
+
+import (
+	"context"
+	"testing"
+
+	"service-info-aggregator/internal/messaging"
+	"service-info-aggregator/internal/tracing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadataPropagation(t *testing.T) {
+	parent := tracing.NewSpanContext()
+	ctx := tracing.ContextWithSpan(context.Background(), parent)
+	ctx = tracing.ContextWithRequestID(ctx, "req-1")
+
+	msg := &messaging.Message{Topic: "events"}
+	messaging.InjectMetadata(ctx, msg)
+
+	contentType, ok := msg.Header("content-type")
+	require.True(t, ok)
+	assert.Equal(t, "application/octet-stream", contentType)
+
+	consumed := messaging.ExtractMetadata(context.Background(), msg)
+	sc, ok := tracing.SpanFromContext(consumed)
+	require.True(t, ok)
+	assert.Equal(t, parent.TraceID, sc.TraceID)
+	assert.NotEqual(t, parent.SpanID, sc.SpanID)
+
+	requestID, ok := tracing.RequestIDFromContext(consumed)
+	require.True(t, ok)
+	assert.Equal(t, "req-1", requestID)
+}
+
+func TestMetadataPropagation_KeepsContentType(t *testing.T) {
+	msg, err := messaging.EncodeCloudEvent("events", testEvent("weather"), messaging.EventModeStructured)
+	require.NoError(t, err)
+
+	messaging.InjectMetadata(context.Background(), msg)
+
+	contentType, _ := msg.Header("content-type")
+	assert.Equal(t, "application/cloudevents+json", contentType)
+	_, ok := msg.Header(tracing.TraceparentHeader)
+	assert.False(t, ok)
+}
@@ -0,0 +1,78 @@
+package redisstream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"service-info-aggregator/internal/messaging"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	fieldKey     = "key"
+	fieldValue   = "value"
+	fieldHeaders = "headers"
+)
+
+type Publisher struct {
+	client *redis.Client
+	maxLen int64
+}
+
+func NewPublisher(client *redis.Client, maxLen int64) *Publisher {
+	return &Publisher{
+		client: client,
+		maxLen: maxLen,
+	}
+}
+
+func (p *Publisher) Publish(ctx context.Context, msg *messaging.Message) error {
+	messaging.InjectMetadata(ctx, msg)
+
+	headers, err := encodeHeaders(msg.Headers)
+	if err != nil {
+		return err
+	}
+
+	return p.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: msg.Topic,
+		MaxLen: p.maxLen,
+		Approx: true,
+		Values: map[string]any{
+			fieldKey:     msg.Key,
+			fieldValue:   msg.Value,
+			fieldHeaders: headers,
+		},
+	}).Err()
+}
+
+func (p *Publisher) Close() {}
+
+func encodeHeaders(headers []messaging.Header) (string, error) {
+	m := make(map[string]string, len(headers))
+	for _, h := range headers {
+		m[h.Key] = string(h.Value)
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal message headers: %w", err)
+	}
+	return string(b), nil
+}
+
+func decodeHeaders(raw string) ([]messaging.Header, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var m map[string]string
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		return nil, fmt.Errorf("could not unmarshal message headers: %w", err)
+	}
+	headers := make([]messaging.Header, 0, len(m))
+	for k, v := range m {
+		headers = append(headers, messaging.Header{Key: k, Value: []byte(v)})
+	}
+	return headers, nil
+}
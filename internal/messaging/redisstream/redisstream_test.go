@@ -0,0 +1,236 @@
+package redisstream_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"service-info-aggregator/internal/messaging"
+	"service-info-aggregator/internal/messaging/redisstream"
+	"service-info-aggregator/internal/tracing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testStream = "events"
+	testGroup  = "aggregator"
+)
+
+func newTestClient(t *testing.T) *redis.Client {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func newTestSubscriber(client *redis.Client) *redisstream.Subscriber {
+	return redisstream.NewSubscriber(client, redisstream.SubscriberConfig{
+		Group:         testGroup,
+		Consumer:      "consumer-1",
+		BatchSize:     1,
+		BlockTimeout:  20 * time.Millisecond,
+		ClaimMinIdle:  10 * time.Millisecond,
+		ClaimInterval: time.Hour,
+	})
+}
+
+type received struct {
+	mu   sync.Mutex
+	msgs []*messaging.Message
+}
+
+func (r *received) handle(_ context.Context, msg *messaging.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.msgs = append(r.msgs, msg)
+	return nil
+}
+
+func (r *received) keys() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keys := make([]string, 0, len(r.msgs))
+	for _, msg := range r.msgs {
+		keys = append(keys, string(msg.Key))
+	}
+	return keys
+}
+
+func run(t *testing.T, subscriber *redisstream.Subscriber, handler messaging.MessageHandler) context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, subscriber.Run(ctx, []string{testStream}, handler))
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return cancel
+}
+
+func TestSubscriber_ConsumesAndAcknowledges(t *testing.T) {
+	client := newTestClient(t)
+	publisher := redisstream.NewPublisher(client, 1000)
+	ctx := context.Background()
+
+	var got received
+	run(t, newTestSubscriber(client), got.handle)
+
+	require.NoError(t, publisher.Publish(ctx, &messaging.Message{
+		Topic:   testStream,
+		Key:     []byte("Moscow"),
+		Value:   []byte(`{"temp":20}`),
+		Headers: []messaging.Header{{Key: "ce_type", Value: []byte("weather")}},
+	}))
+	require.NoError(t, publisher.Publish(ctx, &messaging.Message{Topic: testStream, Key: []byte("London")}))
+
+	require.Eventually(t, func() bool { return len(got.keys()) == 2 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"Moscow", "London"}, got.keys())
+
+	got.mu.Lock()
+	first := got.msgs[0]
+	got.mu.Unlock()
+	assert.Equal(t, testStream, first.Topic)
+	assert.Equal(t, `{"temp":20}`, string(first.Value))
+	eventType, ok := first.Header("ce_type")
+	assert.True(t, ok)
+	assert.Equal(t, "weather", eventType)
+	assert.False(t, first.Timestamp.IsZero())
+
+	require.Eventually(t, func() bool {
+		pending, err := client.XPending(ctx, testStream, testGroup).Result()
+		return err == nil && pending.Count == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSubscriber_ReclaimsStalePendingEntries(t *testing.T) {
+	client := newTestClient(t)
+	publisher := redisstream.NewPublisher(client, 1000)
+	ctx := context.Background()
+
+	require.NoError(t, client.XGroupCreateMkStream(ctx, testStream, testGroup, "0").Err())
+	for _, key := range []string{"Moscow", "London"} {
+		require.NoError(t, publisher.Publish(ctx, &messaging.Message{Topic: testStream, Key: []byte(key)}))
+	}
+
+	// Another consumer reads both entries and dies before acknowledging them.
+	_, err := client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    testGroup,
+		Consumer: "crashed",
+		Streams:  []string{testStream, ">"},
+	}).Result()
+	require.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+
+	var got received
+	run(t, newTestSubscriber(client), got.handle)
+
+	// The batch size is 1, so both entries are only reclaimed by following
+	// the cursor to the end.
+	require.Eventually(t, func() bool { return len(got.keys()) == 2 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"Moscow", "London"}, got.keys())
+
+	pending, err := client.XPending(ctx, testStream, testGroup).Result()
+	require.NoError(t, err)
+	assert.Zero(t, pending.Count)
+}
+
+func TestSubscriber_DeadLettersEntriesAfterMaxDeliveries(t *testing.T) {
+	client := newTestClient(t)
+	publisher := redisstream.NewPublisher(client, 1000)
+	ctx := context.Background()
+
+	subscriber := redisstream.NewSubscriber(client, redisstream.SubscriberConfig{
+		Group:            testGroup,
+		Consumer:         "consumer-1",
+		BatchSize:        10,
+		BlockTimeout:     10 * time.Millisecond,
+		ClaimMinIdle:     time.Millisecond,
+		ClaimInterval:    time.Millisecond,
+		MaxDeliveries:    2,
+		DeadLetterSuffix: ".dlq",
+	})
+
+	var mu sync.Mutex
+	attempts := 0
+	run(t, subscriber, func(ctx context.Context, msg *messaging.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		return errors.New("poison")
+	})
+
+	require.NoError(t, publisher.Publish(ctx, &messaging.Message{Topic: testStream, Key: []byte("Moscow")}))
+
+	require.Eventually(t, func() bool {
+		n, err := client.XLen(ctx, testStream+".dlq").Result()
+		return err == nil && n == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	dead, err := client.XRange(ctx, testStream+".dlq", "-", "+").Result()
+	require.NoError(t, err)
+	assert.Equal(t, "Moscow", dead[0].Values["key"])
+	assert.NotEmpty(t, dead[0].Values["dead_letter_id"])
+
+	pending, err := client.XPending(ctx, testStream, testGroup).Result()
+	require.NoError(t, err)
+	assert.Zero(t, pending.Count)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 2, attempts)
+}
+
+func TestSubscriber_ContinuesTheTraceOfThePublisher(t *testing.T) {
+	client := newTestClient(t)
+	publisher := redisstream.NewPublisher(client, 1000)
+
+	traces := make(chan string, 1)
+	run(t, newTestSubscriber(client), func(ctx context.Context, msg *messaging.Message) error {
+		sc, _ := tracing.SpanFromContext(ctx)
+		traces <- sc.TraceID
+		return nil
+	})
+
+	span := tracing.NewSpanContext()
+	ctx := tracing.ContextWithSpan(context.Background(), span)
+	require.NoError(t, publisher.Publish(ctx, &messaging.Message{Topic: testStream, Key: []byte("Moscow")}))
+
+	select {
+	case traceID := <-traces:
+		assert.Equal(t, span.TraceID, traceID)
+	case <-time.After(time.Second):
+		t.Fatal("message was not consumed")
+	}
+}
+
+func TestSubscriber_StopsDuringReadBackoff(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- newTestSubscriber(client).Run(ctx, []string{testStream}, (&received{}).handle) }()
+
+	// Reads fail from now on, so the subscriber backs off.
+	time.Sleep(50 * time.Millisecond)
+	server.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Run did not return while backing off")
+	}
+}
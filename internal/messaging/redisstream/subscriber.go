@@ -0,0 +1,240 @@
+package redisstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"service-info-aggregator/internal/messaging"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type SubscriberConfig struct {
+	Group         string
+	Consumer      string
+	BatchSize     int64
+	BlockTimeout  time.Duration
+	ClaimMinIdle  time.Duration
+	ClaimInterval time.Duration
+
+	// MaxDeliveries moves a reclaimed entry to the stream named after its
+	// own plus DeadLetterSuffix once it was delivered more often than that
+	// without being acknowledged; without a suffix it is dropped. 0 retries
+	// it forever.
+	MaxDeliveries    int64
+	DeadLetterSuffix string
+}
+
+const (
+	readBackoff = time.Second
+
+	fieldDeadLetterID = "dead_letter_id"
+)
+
+type Subscriber struct {
+	client *redis.Client
+	cfg    SubscriberConfig
+}
+
+func NewSubscriber(client *redis.Client, cfg SubscriberConfig) *Subscriber {
+	return &Subscriber{
+		client: client,
+		cfg:    cfg,
+	}
+}
+
+func (s *Subscriber) Run(ctx context.Context, topics []string, handler messaging.MessageHandler) error {
+	for _, stream := range topics {
+		err := s.client.XGroupCreateMkStream(ctx, stream, s.cfg.Group, "0").Err()
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return fmt.Errorf("could not create consumer group for stream %s: %w", stream, err)
+		}
+	}
+
+	streams := make([]string, 0, len(topics)*2)
+	streams = append(streams, topics...)
+	for range topics {
+		streams = append(streams, ">")
+	}
+
+	var lastClaim time.Time
+
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if time.Since(lastClaim) >= s.cfg.ClaimInterval {
+			s.reclaim(ctx, topics, handler)
+			lastClaim = time.Now()
+		}
+
+		res, err := s.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    s.cfg.Group,
+			Consumer: s.cfg.Consumer,
+			Streams:  streams,
+			Count:    s.cfg.BatchSize,
+			Block:    s.cfg.BlockTimeout,
+		}).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) || ctx.Err() != nil {
+				continue
+			}
+			slog.Error("redis stream read failed", "error", err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(readBackoff):
+			}
+			continue
+		}
+
+		for _, stream := range res {
+			for _, xmsg := range stream.Messages {
+				s.process(ctx, stream.Stream, xmsg, handler)
+			}
+		}
+	}
+}
+
+// reclaim takes over entries that other consumers read but never
+// acknowledged, e.g. because they crashed mid-processing or the handler
+// failed. Entries delivered more than MaxDeliveries times are dead-lettered
+// instead, so a poison message is not retried forever.
+func (s *Subscriber) reclaim(ctx context.Context, topics []string, handler messaging.MessageHandler) {
+	for _, stream := range topics {
+		start := "0-0"
+		for {
+			claimed, next, err := s.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+				Stream:   stream,
+				Group:    s.cfg.Group,
+				Consumer: s.cfg.Consumer,
+				MinIdle:  s.cfg.ClaimMinIdle,
+				Start:    start,
+				Count:    s.cfg.BatchSize,
+			}).Result()
+			if err != nil {
+				slog.Error("redis stream reclaim failed", "stream", stream, "error", err)
+				break
+			}
+
+			for _, xmsg := range claimed {
+				if s.exhausted(ctx, stream, xmsg.ID) {
+					s.deadLetter(ctx, stream, xmsg)
+					continue
+				}
+				slog.Info("reclaimed pending stream entry", "stream", stream, "id", xmsg.ID)
+				s.process(ctx, stream, xmsg, handler)
+			}
+
+			// A page may claim nothing when its entries are not idle long
+			// enough; only the "0-0" cursor means the scan is done.
+			if next == "0-0" {
+				break
+			}
+			start = next
+		}
+	}
+}
+
+// exhausted reports whether the entry was delivered more than MaxDeliveries
+// times, counting the claim that just took it.
+func (s *Subscriber) exhausted(ctx context.Context, stream, id string) bool {
+	if s.cfg.MaxDeliveries <= 0 {
+		return false
+	}
+
+	pending, err := s.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: stream,
+		Group:  s.cfg.Group,
+		Start:  id,
+		End:    id,
+		Count:  1,
+	}).Result()
+	if err != nil || len(pending) == 0 {
+		if err != nil {
+			slog.Error("redis stream delivery count lookup failed", "stream", stream, "id", id, "error", err)
+		}
+		return false
+	}
+	return pending[0].RetryCount > s.cfg.MaxDeliveries
+}
+
+// deadLetter copies the entry to the dead-letter stream and acknowledges it.
+// It stays pending when the copy fails, to be tried again on the next claim.
+func (s *Subscriber) deadLetter(ctx context.Context, stream string, xmsg redis.XMessage) {
+	if s.cfg.DeadLetterSuffix == "" {
+		slog.Warn("dropped undeliverable stream entry", "stream", stream, "id", xmsg.ID)
+		s.ack(ctx, stream, xmsg.ID)
+		return
+	}
+
+	values := make(map[string]any, len(xmsg.Values)+1)
+	for k, v := range xmsg.Values {
+		values[k] = v
+	}
+	values[fieldDeadLetterID] = xmsg.ID
+
+	target := stream + s.cfg.DeadLetterSuffix
+	if err := s.client.XAdd(ctx, &redis.XAddArgs{Stream: target, Values: values}).Err(); err != nil {
+		slog.Error("redis stream dead-lettering failed", "stream", stream, "id", xmsg.ID, "error", err)
+		return
+	}
+	slog.Warn("dead-lettered stream entry", "stream", stream, "id", xmsg.ID, "dead_letter_stream", target)
+	s.ack(ctx, stream, xmsg.ID)
+}
+
+func (s *Subscriber) process(ctx context.Context, stream string, xmsg redis.XMessage, handler messaging.MessageHandler) {
+	msg, err := toMessage(stream, xmsg)
+	if err != nil {
+		slog.Error("invalid stream entry", "stream", stream, "id", xmsg.ID, "error", err)
+		s.ack(ctx, stream, xmsg.ID)
+		return
+	}
+
+	if err := handler(messaging.ExtractMetadata(ctx, msg), msg); err != nil {
+		slog.Error("message processing failed", "stream", stream, "id", xmsg.ID, "error", err)
+		return
+	}
+
+	s.ack(ctx, stream, xmsg.ID)
+}
+
+func (s *Subscriber) ack(ctx context.Context, stream, id string) {
+	if err := s.client.XAck(ctx, stream, s.cfg.Group, id).Err(); err != nil {
+		slog.Error("redis stream ack failed", "stream", stream, "id", id, "error", err)
+	}
+}
+
+func (s *Subscriber) Close() {}
+
+func toMessage(stream string, xmsg redis.XMessage) (*messaging.Message, error) {
+	key, _ := xmsg.Values[fieldKey].(string)
+	value, _ := xmsg.Values[fieldValue].(string)
+	rawHeaders, _ := xmsg.Values[fieldHeaders].(string)
+
+	headers, err := decodeHeaders(rawHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &messaging.Message{
+		Topic:   stream,
+		Key:     []byte(key),
+		Value:   []byte(value),
+		Headers: headers,
+	}
+
+	if ms, _, ok := strings.Cut(xmsg.ID, "-"); ok {
+		if millis, err := strconv.ParseInt(ms, 10, 64); err == nil {
+			msg.Timestamp = time.UnixMilli(millis).UTC()
+		}
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,152 @@
+package schemaregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SchemaTypeAvro     = "AVRO"
+	SchemaTypeProtobuf = "PROTOBUF"
+
+	contentType = "application/vnd.schemaregistry.v1+json"
+)
+
+type Schema struct {
+	Type   string
+	Schema string
+}
+
+type Client interface {
+	Register(ctx context.Context, subject string, schema Schema) (int, error)
+	GetByID(ctx context.Context, id int) (Schema, error)
+}
+
+type HTTPClient struct {
+	baseURL  string
+	username string
+	password string
+	client   *http.Client
+
+	mu   sync.RWMutex
+	ids  map[string]int
+	byID map[int]Schema
+}
+
+func NewHTTPClient(baseURL, username, password string, timeout time.Duration) *HTTPClient {
+	return &HTTPClient{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		username: username,
+		password: password,
+		client:   &http.Client{Timeout: timeout},
+		ids:      make(map[string]int),
+		byID:     make(map[int]Schema),
+	}
+}
+
+func (c *HTTPClient) Register(ctx context.Context, subject string, schema Schema) (int, error) {
+	cacheKey := subject + "\x00" + schema.Type + "\x00" + schema.Schema
+
+	c.mu.RLock()
+	id, ok := c.ids[cacheKey]
+	c.mu.RUnlock()
+	if ok {
+		return id, nil
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"schemaType": schema.Type,
+		"schema":     schema.Schema,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var resp struct {
+		ID int `json:"id"`
+	}
+	path := "/subjects/" + url.PathEscape(subject) + "/versions"
+	if err := c.do(ctx, http.MethodPost, path, body, &resp); err != nil {
+		return 0, fmt.Errorf("could not register schema for subject %s: %w", subject, err)
+	}
+
+	c.mu.Lock()
+	c.ids[cacheKey] = resp.ID
+	c.byID[resp.ID] = schema
+	c.mu.Unlock()
+
+	return resp.ID, nil
+}
+
+func (c *HTTPClient) GetByID(ctx context.Context, id int) (Schema, error) {
+	c.mu.RLock()
+	schema, ok := c.byID[id]
+	c.mu.RUnlock()
+	if ok {
+		return schema, nil
+	}
+
+	var resp struct {
+		Schema     string `json:"schema"`
+		SchemaType string `json:"schemaType"`
+	}
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/schemas/ids/%d", id), nil, &resp); err != nil {
+		return Schema{}, fmt.Errorf("could not fetch schema %d: %w", id, err)
+	}
+
+	// The registry omits schemaType for Avro, its original format.
+	schema = Schema{Type: resp.SchemaType, Schema: resp.Schema}
+	if schema.Type == "" {
+		schema.Type = SchemaTypeAvro
+	}
+
+	c.mu.Lock()
+	c.byID[id] = schema
+	c.mu.Unlock()
+
+	return schema, nil
+}
+
+func (c *HTTPClient) do(ctx context.Context, method, path string, body []byte, out any) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", contentType)
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var regErr struct {
+			ErrorCode int    `json:"error_code"`
+			Message   string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&regErr)
+		return fmt.Errorf("schema registry returned %d: %s", resp.StatusCode, regErr.Message)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
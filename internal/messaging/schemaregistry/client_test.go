@@ -0,0 +1,59 @@
+package schemaregistry_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"service-info-aggregator/internal/messaging/schemaregistry"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPClient_RegisterAndGet(t *testing.T) {
+	var registerCalls, getCalls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/subjects/events-value/versions":
+			registerCalls++
+			var body map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, schemaregistry.SchemaTypeProtobuf, body["schemaType"])
+			json.NewEncoder(w).Encode(map[string]int{"id": 7})
+		case r.Method == http.MethodGet && r.URL.Path == "/schemas/ids/3":
+			getCalls++
+			json.NewEncoder(w).Encode(map[string]string{"schema": `"string"`})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]any{"error_code": 40403, "message": "Schema not found"})
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	client := schemaregistry.NewHTTPClient(srv.URL, "", "", time.Second)
+
+	schema := schemaregistry.Schema{Type: schemaregistry.SchemaTypeProtobuf, Schema: "syntax = \"proto3\";"}
+	for i := 0; i < 2; i++ {
+		id, err := client.Register(ctx, "events-value", schema)
+		require.NoError(t, err)
+		assert.Equal(t, 7, id)
+	}
+	assert.Equal(t, 1, registerCalls)
+
+	got, err := client.GetByID(ctx, 7)
+	require.NoError(t, err)
+	assert.Equal(t, schema, got)
+
+	got, err = client.GetByID(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, schemaregistry.SchemaTypeAvro, got.Type)
+	assert.Equal(t, 1, getCalls)
+
+	_, err = client.GetByID(ctx, 99)
+	require.ErrorContains(t, err, "Schema not found")
+}
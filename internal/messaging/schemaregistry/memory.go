@@ -0,0 +1,51 @@
+package schemaregistry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// MemoryClient is an in-process registry for tests and local runs without a
+// schema registry deployment.
+type MemoryClient struct {
+	mu     sync.Mutex
+	nextID int
+	ids    map[string]int
+	byID   map[int]Schema
+}
+
+func NewMemoryClient() *MemoryClient {
+	return &MemoryClient{
+		nextID: 1,
+		ids:    make(map[string]int),
+		byID:   make(map[int]Schema),
+	}
+}
+
+func (c *MemoryClient) Register(ctx context.Context, subject string, schema Schema) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := schema.Type + "\x00" + schema.Schema
+	if id, ok := c.ids[key]; ok {
+		return id, nil
+	}
+
+	id := c.nextID
+	c.nextID++
+	c.ids[key] = id
+	c.byID[id] = schema
+	return id, nil
+}
+
+func (c *MemoryClient) GetByID(ctx context.Context, id int) (Schema, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	schema, ok := c.byID[id]
+	if !ok {
+		return Schema{}, fmt.Errorf("schema %d not found", id)
+	}
+	return schema, nil
+}
@@ -0,0 +1,84 @@
+package serde
+
+import (
+	"fmt"
+	"time"
+
+	"service-info-aggregator/internal/model/events"
+
+	"github.com/hamba/avro/v2"
+)
+
+const ContentTypeAvro = "application/avro"
+
+const cloudEventAvroSchema = `{
+  "type": "record",
+  "name": "CloudEvent",
+  "namespace": "aggregator.events.v1",
+  "fields": [
+    {"name": "id", "type": "string"},
+    {"name": "source", "type": "string"},
+    {"name": "specversion", "type": "string"},
+    {"name": "type", "type": "string"},
+    {"name": "subject", "type": "string", "default": ""},
+    {"name": "time", "type": {"type": "long", "logicalType": "timestamp-micros"}},
+    {"name": "datacontenttype", "type": "string", "default": ""},
+    {"name": "dataschema", "type": "string", "default": ""},
+    {"name": "data", "type": "bytes"},
+    {"name": "fencingtoken", "type": "long", "default": 0}
+  ]
+}`
+
+var parsedCloudEventAvroSchema = avro.MustParse(cloudEventAvroSchema)
+
+type avroCloudEvent struct {
+	ID              string    `avro:"id"`
+	Source          string    `avro:"source"`
+	SpecVersion     string    `avro:"specversion"`
+	Type            string    `avro:"type"`
+	Subject         string    `avro:"subject"`
+	Time            time.Time `avro:"time"`
+	DataContentType string    `avro:"datacontenttype"`
+	DataSchema      string    `avro:"dataschema"`
+	Data            []byte    `avro:"data"`
+	FencingToken    int64     `avro:"fencingtoken"`
+}
+
+func encodeAvro(event *events.CloudEvent) ([]byte, error) {
+	b, err := avro.Marshal(parsedCloudEventAvroSchema, avroCloudEvent{
+		ID:              event.ID,
+		Source:          event.Source,
+		SpecVersion:     event.SpecVersion,
+		Type:            event.Type,
+		Subject:         event.Subject,
+		Time:            event.Time.UTC(),
+		DataContentType: event.DataContentType,
+		DataSchema:      event.DataSchema,
+		Data:            event.Data,
+		FencingToken:    event.FencingToken,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not encode avro event: %w", err)
+	}
+	return b, nil
+}
+
+func decodeAvro(writerSchema avro.Schema, data []byte) (*events.CloudEvent, error) {
+	var record avroCloudEvent
+	if err := avro.Unmarshal(writerSchema, data, &record); err != nil {
+		return nil, fmt.Errorf("could not decode avro event: %w", err)
+	}
+
+	return &events.CloudEvent{
+		ID:              record.ID,
+		Source:          record.Source,
+		SpecVersion:     record.SpecVersion,
+		Type:            record.Type,
+		Subject:         record.Subject,
+		Time:            record.Time.UTC(),
+		DataContentType: record.DataContentType,
+		DataSchema:      record.DataSchema,
+		Data:            record.Data,
+		FencingToken:    record.FencingToken,
+	}, nil
+}
@@ -0,0 +1,139 @@
+package serde
+
+import (
+	"fmt"
+	"time"
+
+	"service-info-aggregator/internal/model/events"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const ContentTypeProtobuf = "application/x-protobuf"
+
+const cloudEventProtoSchema = `syntax = "proto3";
+
+package aggregator.events.v1;
+
+message CloudEvent {
+  string id = 1;
+  string source = 2;
+  string spec_version = 3;
+  string type = 4;
+  string subject = 5;
+  string time = 6;
+  string data_content_type = 7;
+  string data_schema = 8;
+  bytes data = 9;
+  int64 fencing_token = 10;
+}
+`
+
+const (
+	fieldID protowire.Number = iota + 1
+	fieldSource
+	fieldSpecVersion
+	fieldType
+	fieldSubject
+	fieldTime
+	fieldDataContentType
+	fieldDataSchema
+	fieldData
+	fieldFencingToken
+)
+
+func encodeProtobuf(event *events.CloudEvent) []byte {
+	var b []byte
+	appendString := func(num protowire.Number, v string) {
+		if v == "" {
+			return
+		}
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		b = protowire.AppendString(b, v)
+	}
+
+	appendString(fieldID, event.ID)
+	appendString(fieldSource, event.Source)
+	appendString(fieldSpecVersion, event.SpecVersion)
+	appendString(fieldType, event.Type)
+	appendString(fieldSubject, event.Subject)
+	if !event.Time.IsZero() {
+		appendString(fieldTime, event.Time.UTC().Format(time.RFC3339Nano))
+	}
+	appendString(fieldDataContentType, event.DataContentType)
+	appendString(fieldDataSchema, event.DataSchema)
+	if len(event.Data) > 0 {
+		b = protowire.AppendTag(b, fieldData, protowire.BytesType)
+		b = protowire.AppendBytes(b, event.Data)
+	}
+	if event.FencingToken != 0 {
+		b = protowire.AppendTag(b, fieldFencingToken, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(event.FencingToken))
+	}
+	return b
+}
+
+func decodeProtobuf(data []byte) (*events.CloudEvent, error) {
+	event := &events.CloudEvent{}
+
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("could not decode protobuf event: %w", protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		if num == fieldFencingToken && typ == protowire.VarintType {
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return nil, fmt.Errorf("could not decode protobuf event: %w", protowire.ParseError(n))
+			}
+			data = data[n:]
+			event.FencingToken = int64(v)
+			continue
+		}
+
+		if typ != protowire.BytesType || num < fieldID || num > fieldData {
+			// Unknown fields come from newer schema versions and are skipped.
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return nil, fmt.Errorf("could not decode protobuf event: %w", protowire.ParseError(n))
+			}
+			data = data[n:]
+			continue
+		}
+
+		v, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return nil, fmt.Errorf("could not decode protobuf event: %w", protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		switch num {
+		case fieldID:
+			event.ID = string(v)
+		case fieldSource:
+			event.Source = string(v)
+		case fieldSpecVersion:
+			event.SpecVersion = string(v)
+		case fieldType:
+			event.Type = string(v)
+		case fieldSubject:
+			event.Subject = string(v)
+		case fieldTime:
+			t, err := time.Parse(time.RFC3339Nano, string(v))
+			if err != nil {
+				return nil, fmt.Errorf("invalid protobuf event time: %w", err)
+			}
+			event.Time = t
+		case fieldDataContentType:
+			event.DataContentType = string(v)
+		case fieldDataSchema:
+			event.DataSchema = string(v)
+		case fieldData:
+			event.Data = append([]byte(nil), v...)
+		}
+	}
+
+	return event, nil
+}
@@ -0,0 +1,146 @@
+package serde
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"service-info-aggregator/internal/messaging"
+	"service-info-aggregator/internal/messaging/schemaregistry"
+	"service-info-aggregator/internal/model/events"
+
+	"github.com/hamba/avro/v2"
+)
+
+const (
+	FormatAvro     = "avro"
+	FormatProtobuf = "protobuf"
+
+	contentTypeHeader = "content-type"
+)
+
+// RegistrySerializer writes events in the schema registry wire format and
+// reads any registered format back. Messages that are not framed, such as
+// CloudEvents JSON published before the switch, go to the fallback.
+type RegistrySerializer struct {
+	format   string
+	registry schemaregistry.Client
+	fallback messaging.EventSerializer
+
+	mu          sync.Mutex
+	avroSchemas map[int]avro.Schema
+}
+
+func NewRegistrySerializer(format string, registry schemaregistry.Client, fallback messaging.EventSerializer) (*RegistrySerializer, error) {
+	if format != FormatAvro && format != FormatProtobuf {
+		return nil, fmt.Errorf("unsupported serialization format: %s", format)
+	}
+
+	return &RegistrySerializer{
+		format:      format,
+		registry:    registry,
+		fallback:    fallback,
+		avroSchemas: make(map[int]avro.Schema),
+	}, nil
+}
+
+func (s *RegistrySerializer) Serialize(ctx context.Context, topic string, event *events.CloudEvent) (*messaging.Message, error) {
+	var (
+		schema      schemaregistry.Schema
+		prefix      []byte
+		payload     []byte
+		contentType string
+		err         error
+	)
+
+	switch s.format {
+	case FormatAvro:
+		schema = schemaregistry.Schema{Type: schemaregistry.SchemaTypeAvro, Schema: cloudEventAvroSchema}
+		contentType = ContentTypeAvro
+		payload, err = encodeAvro(event)
+		if err != nil {
+			return nil, err
+		}
+	case FormatProtobuf:
+		schema = schemaregistry.Schema{Type: schemaregistry.SchemaTypeProtobuf, Schema: cloudEventProtoSchema}
+		contentType = ContentTypeProtobuf
+		prefix = firstMessageIndexes
+		payload = encodeProtobuf(event)
+	}
+
+	id, err := s.registry.Register(ctx, topic+"-value", schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return &messaging.Message{
+		Topic:   topic,
+		Key:     []byte(event.Subject),
+		Value:   frame(id, prefix, payload),
+		Headers: []messaging.Header{{Key: contentTypeHeader, Value: []byte(contentType)}},
+	}, nil
+}
+
+func (s *RegistrySerializer) Deserialize(ctx context.Context, msg *messaging.Message) (*events.CloudEvent, error) {
+	if !isFramed(msg.Value) {
+		return s.fallback.Deserialize(ctx, msg)
+	}
+
+	id, data, err := unframe(msg.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := s.registry.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var event *events.CloudEvent
+	switch schema.Type {
+	case schemaregistry.SchemaTypeAvro:
+		writerSchema, err := s.avroSchema(id, schema.Schema)
+		if err != nil {
+			return nil, err
+		}
+		event, err = decodeAvro(writerSchema, data)
+		if err != nil {
+			return nil, err
+		}
+	case schemaregistry.SchemaTypeProtobuf:
+		indexes, rest, err := readMessageIndexes(data)
+		if err != nil {
+			return nil, err
+		}
+		if len(indexes) != 1 || indexes[0] != 0 {
+			return nil, fmt.Errorf("unexpected protobuf message indexes %v in schema %d", indexes, id)
+		}
+		event, err = decodeProtobuf(rest)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported schema type %q for schema %d", schema.Type, id)
+	}
+
+	if err := event.Validate(); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (s *RegistrySerializer) avroSchema(id int, raw string) (avro.Schema, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if schema, ok := s.avroSchemas[id]; ok {
+		return schema, nil
+	}
+
+	schema, err := avro.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse avro schema %d: %w", id, err)
+	}
+	s.avroSchemas[id] = schema
+	return schema, nil
+}
@@ -0,0 +1,90 @@
+package serde_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"service-info-aggregator/internal/messaging"
+	"service-info-aggregator/internal/messaging/schemaregistry"
+	"service-info-aggregator/internal/messaging/serde"
+	"service-info-aggregator/internal/model/dto"
+	"service-info-aggregator/internal/model/events"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEvent(t *testing.T) *events.CloudEvent {
+	event, err := events.NewCloudEvent("/test", "weather", "Moscow", dto.WeatherResponse{City: "Moscow", Temp: 20})
+	require.NoError(t, err)
+	return event
+}
+
+func TestRegistrySerializer_RoundTrip(t *testing.T) {
+	for _, format := range []string{serde.FormatAvro, serde.FormatProtobuf} {
+		t.Run(format, func(t *testing.T) {
+			ctx := context.Background()
+			registry := schemaregistry.NewMemoryClient()
+			s, err := serde.NewRegistrySerializer(format, registry, messaging.NewJSONSerializer(messaging.EventModeStructured))
+			require.NoError(t, err)
+
+			event := newTestEvent(t)
+			event.FencingToken = 3
+			msg, err := s.Serialize(ctx, "events", event)
+			require.NoError(t, err)
+			assert.Equal(t, byte(0), msg.Value[0])
+			assert.Equal(t, "Moscow", string(msg.Key))
+
+			decoded, err := s.Deserialize(ctx, msg)
+			require.NoError(t, err)
+			assert.Equal(t, event.ID, decoded.ID)
+			assert.Equal(t, event.Type, decoded.Type)
+			assert.Equal(t, event.Subject, decoded.Subject)
+			assert.Equal(t, event.FencingToken, decoded.FencingToken)
+			assert.True(t, event.Time.Truncate(time.Microsecond).Equal(decoded.Time.Truncate(time.Microsecond)))
+			assert.JSONEq(t, string(event.Data), string(decoded.Data))
+		})
+	}
+}
+
+func TestRegistrySerializer_ReadsOtherFormat(t *testing.T) {
+	ctx := context.Background()
+	registry := schemaregistry.NewMemoryClient()
+	fallback := messaging.NewJSONSerializer(messaging.EventModeStructured)
+
+	writer, err := serde.NewRegistrySerializer(serde.FormatProtobuf, registry, fallback)
+	require.NoError(t, err)
+	reader, err := serde.NewRegistrySerializer(serde.FormatAvro, registry, fallback)
+	require.NoError(t, err)
+
+	msg, err := writer.Serialize(ctx, "events", newTestEvent(t))
+	require.NoError(t, err)
+
+	decoded, err := reader.Deserialize(ctx, msg)
+	require.NoError(t, err)
+	assert.Equal(t, "weather", decoded.Type)
+}
+
+func TestRegistrySerializer_FallsBackToJSON(t *testing.T) {
+	ctx := context.Background()
+	fallback := messaging.NewJSONSerializer(messaging.EventModeBinary)
+	s, err := serde.NewRegistrySerializer(serde.FormatAvro, schemaregistry.NewMemoryClient(), fallback)
+	require.NoError(t, err)
+
+	event := newTestEvent(t)
+	msg, err := fallback.Serialize(ctx, "events", event)
+	require.NoError(t, err)
+
+	decoded, err := s.Deserialize(ctx, msg)
+	require.NoError(t, err)
+	assert.Equal(t, event.ID, decoded.ID)
+}
+
+func TestRegistrySerializer_UnknownSchema(t *testing.T) {
+	s, err := serde.NewRegistrySerializer(serde.FormatAvro, schemaregistry.NewMemoryClient(), nil)
+	require.NoError(t, err)
+
+	_, err = s.Deserialize(context.Background(), &messaging.Message{Value: []byte{0, 0, 0, 0, 42, 1}})
+	require.Error(t, err)
+}
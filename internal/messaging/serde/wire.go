@@ -0,0 +1,60 @@
+package serde
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Confluent wire format: magic byte 0, 4-byte big-endian schema ID, then the
+// encoded record. Protobuf records are additionally prefixed with the path of
+// message indexes inside the .proto file.
+const (
+	magicByte  = 0
+	headerSize = 5
+)
+
+func isFramed(value []byte) bool {
+	return len(value) >= headerSize && value[0] == magicByte
+}
+
+func frame(schemaID int, prefix, payload []byte) []byte {
+	out := make([]byte, headerSize, headerSize+len(prefix)+len(payload))
+	out[0] = magicByte
+	binary.BigEndian.PutUint32(out[1:headerSize], uint32(schemaID))
+	out = append(out, prefix...)
+	return append(out, payload...)
+}
+
+func unframe(value []byte) (int, []byte, error) {
+	if !isFramed(value) {
+		return 0, nil, errors.New("message is not in schema registry wire format")
+	}
+	return int(binary.BigEndian.Uint32(value[1:headerSize])), value[headerSize:], nil
+}
+
+// firstMessageIndexes refers to the first message type declared in the schema;
+// the format allows the [0] path to be shortened to a single zero.
+var firstMessageIndexes = []byte{0}
+
+func readMessageIndexes(data []byte) ([]int64, []byte, error) {
+	count, n := binary.Varint(data)
+	if n <= 0 {
+		return nil, nil, errors.New("invalid protobuf message index count")
+	}
+	data = data[n:]
+	if count == 0 {
+		return []int64{0}, data, nil
+	}
+
+	indexes := make([]int64, 0, count)
+	for i := int64(0); i < count; i++ {
+		idx, n := binary.Varint(data)
+		if n <= 0 {
+			return nil, nil, fmt.Errorf("invalid protobuf message index %d", i)
+		}
+		indexes = append(indexes, idx)
+		data = data[n:]
+	}
+	return indexes, data, nil
+}
@@ -0,0 +1,28 @@
+package messaging
+
+import (
+	"context"
+
+	"service-info-aggregator/internal/model/events"
+)
+
+type EventSerializer interface {
+	Serialize(ctx context.Context, topic string, event *events.CloudEvent) (*Message, error)
+	Deserialize(ctx context.Context, msg *Message) (*events.CloudEvent, error)
+}
+
+type JSONSerializer struct {
+	mode string
+}
+
+func NewJSONSerializer(mode string) *JSONSerializer {
+	return &JSONSerializer{mode: mode}
+}
+
+func (s *JSONSerializer) Serialize(ctx context.Context, topic string, event *events.CloudEvent) (*Message, error) {
+	return EncodeCloudEvent(topic, event, s.mode)
+}
+
+func (s *JSONSerializer) Deserialize(ctx context.Context, msg *Message) (*events.CloudEvent, error) {
+	return DecodeCloudEvent(msg)
+}
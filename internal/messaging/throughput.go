@@ -0,0 +1,112 @@
+package messaging
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"service-info-aggregator/internal/model/events"
+)
+
+const throughputWindow = 60
+
+type TypeThroughput struct {
+	Type         string  `json:"type"`
+	Handled      uint64  `json:"handled"`
+	Failed       uint64  `json:"failed"`
+	RatePerSec   float64 `json:"rate_per_sec"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+}
+
+type throughputBucket struct {
+	second  int64
+	count   uint64
+	latency time.Duration
+}
+
+type typeCounters struct {
+	handled uint64
+	failed  uint64
+	buckets [throughputWindow]throughputBucket
+}
+
+// ThroughputTracker keeps per event type totals and the rate and average
+// handler latency over the last minute, for the admin endpoint.
+type ThroughputTracker struct {
+	mu     sync.Mutex
+	byType map[string]*typeCounters
+	now    func() time.Time
+}
+
+func NewThroughputTracker() *ThroughputTracker {
+	return &ThroughputTracker{
+		byType: make(map[string]*typeCounters),
+		now:    time.Now,
+	}
+}
+
+func (t *ThroughputTracker) Middleware(next HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, event *events.CloudEvent) error {
+		started := t.now()
+		err := next(ctx, event)
+		t.record(event.Type, t.now().Sub(started), err != nil)
+		return err
+	}
+}
+
+func (t *ThroughputTracker) record(eventType string, latency time.Duration, failed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c, ok := t.byType[eventType]
+	if !ok {
+		c = &typeCounters{}
+		t.byType[eventType] = c
+	}
+
+	c.handled++
+	if failed {
+		c.failed++
+	}
+
+	second := t.now().Unix()
+	b := &c.buckets[second%throughputWindow]
+	if b.second != second {
+		*b = throughputBucket{second: second}
+	}
+	b.count++
+	b.latency += latency
+}
+
+func (t *ThroughputTracker) Snapshot() []TypeThroughput {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now().Unix()
+	result := make([]TypeThroughput, 0, len(t.byType))
+	for eventType, c := range t.byType {
+		var count uint64
+		var latency time.Duration
+		for _, b := range c.buckets {
+			if now-b.second < throughputWindow {
+				count += b.count
+				latency += b.latency
+			}
+		}
+
+		tt := TypeThroughput{
+			Type:       eventType,
+			Handled:    c.handled,
+			Failed:     c.failed,
+			RatePerSec: float64(count) / throughputWindow,
+		}
+		if count > 0 {
+			tt.AvgLatencyMs = float64(latency.Microseconds()) / 1000 / float64(count)
+		}
+		result = append(result, tt)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Type < result[j].Type })
+	return result
+}
@@ -0,0 +1,36 @@
+package messaging_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"service-info-aggregator/internal/messaging"
+	"service-info-aggregator/internal/model/events"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThroughputTracker_CountsPerType(t *testing.T) {
+	tracker := messaging.NewThroughputTracker()
+
+	ok := tracker.Middleware(func(ctx context.Context, event *events.CloudEvent) error { return nil })
+	failing := tracker.Middleware(func(ctx context.Context, event *events.CloudEvent) error { return errors.New("boom") })
+
+	require.NoError(t, ok(context.Background(), testEvent("weather")))
+	require.NoError(t, ok(context.Background(), testEvent("weather")))
+	require.Error(t, failing(context.Background(), testEvent("news")))
+
+	snapshot := tracker.Snapshot()
+	require.Len(t, snapshot, 2)
+
+	assert.Equal(t, "news", snapshot[0].Type)
+	assert.Equal(t, uint64(1), snapshot[0].Handled)
+	assert.Equal(t, uint64(1), snapshot[0].Failed)
+
+	assert.Equal(t, "weather", snapshot[1].Type)
+	assert.Equal(t, uint64(2), snapshot[1].Handled)
+	assert.Zero(t, snapshot[1].Failed)
+	assert.InDelta(t, 2.0/60, snapshot[1].RatePerSec, 1e-9)
+}
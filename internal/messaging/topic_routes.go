@@ -0,0 +1,45 @@
+package messaging
+
+import "sort"
+
+// EventTypeHeader duplicates the CloudEvent type outside the payload so
+// consumers can skip events they do not handle without deserializing them.
+const EventTypeHeader = "event-type"
+
+// TopicRoutes maps event types (provider names) to the topic their events are
+// published to; unlisted types go to the default topic.
+type TopicRoutes struct {
+	defaultTopic string
+	byType       map[string]string
+}
+
+func NewTopicRoutes(defaultTopic string, byType map[string]string) *TopicRoutes {
+	routes := make(map[string]string, len(byType))
+	for eventType, topic := range byType {
+		if topic != "" {
+			routes[eventType] = topic
+		}
+	}
+	return &TopicRoutes{defaultTopic: defaultTopic, byType: routes}
+}
+
+func (r *TopicRoutes) Topic(eventType string) string {
+	if topic, ok := r.byType[eventType]; ok {
+		return topic
+	}
+	return r.defaultTopic
+}
+
+// Topics returns every distinct topic of the table, sorted.
+func (r *TopicRoutes) Topics() []string {
+	seen := map[string]bool{r.defaultTopic: true}
+	topics := []string{r.defaultTopic}
+	for _, topic := range r.byType {
+		if !seen[topic] {
+			seen[topic] = true
+			topics = append(topics, topic)
+		}
+	}
+	sort.Strings(topics)
+	return topics
+}
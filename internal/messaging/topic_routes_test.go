@@ -0,0 +1,44 @@
+package messaging_test
+
+import (
+	"context"
+	"testing"
+
+	"service-info-aggregator/internal/messaging"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopicRoutes(t *testing.T) {
+	routes := messaging.NewTopicRoutes("events.default", map[string]string{
+		"weather": "events.weather",
+		"news":    "events.news",
+		"rates":   "events.default",
+	})
+
+	assert.Equal(t, "events.weather", routes.Topic("weather"))
+	assert.Equal(t, "events.default", routes.Topic("traffic"))
+	assert.Equal(t, []string{"events.default", "events.news", "events.weather"}, routes.Topics())
+}
+
+func TestDispatcher_SkipsUnhandledTypesByHeader(t *testing.T) {
+	var calls []string
+	router := messaging.NewEventRouter(
+		messaging.WithHandlers(&recordingHandler{eventType: "weather", name: "weather", calls: &calls}),
+	)
+	dispatcher := messaging.NewDispatcher(messaging.NewJSONSerializer(messaging.EventModeStructured), router, nil, 0, 0)
+
+	skipped := &messaging.Message{
+		Value:   []byte("not even json"),
+		Headers: []messaging.Header{{Key: messaging.EventTypeHeader, Value: []byte("news")}},
+	}
+	require.NoError(t, dispatcher.Dispatch(context.Background(), skipped))
+
+	msg, err := messaging.EncodeCloudEvent("events.weather", testEvent("weather"), messaging.EventModeStructured)
+	require.NoError(t, err)
+	msg.Headers = append(msg.Headers, messaging.Header{Key: messaging.EventTypeHeader, Value: []byte("weather")})
+	require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+
+	assert.Equal(t, []string{"weather"}, calls)
+}
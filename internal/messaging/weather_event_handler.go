@@ -0,0 +1,61 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"service-info-aggregator/internal/metrics"
+	"service-info-aggregator/internal/model/events"
+	"service-info-aggregator/internal/repository/aggregation_data"
+)
+
+type WeatherEventHandler struct {
+	cache *aggregation_data.RedisRepository
+	ttl   time.Duration
+}
+
+func NewWeatherEventHandler(c *aggregation_data.RedisRepository, ttl time.Duration) *WeatherEventHandler {
+	return &WeatherEventHandler{
+		cache: c,
+		ttl:   ttl,
+	}
+}
+
+func (h *WeatherEventHandler) Type() string {
+	return "weather"
+}
+
+func (h *WeatherEventHandler) Handle(ctx context.Context, event *events.CloudEvent) error {
+	if !json.Valid(event.Data) {
+		return fmt.Errorf("invalid weather payload in event %s", event.ID)
+	}
+
+	// Events without a time are taken as current rather than as older than
+	// everything cached.
+	updatedAt := event.Time
+	if updatedAt.IsZero() {
+		updatedAt = time.Now()
+	}
+
+	cacheKey := "weather:" + event.Subject
+	applied, err := h.cache.SetIfNewer(ctx, cacheKey, string(event.Data), updatedAt, event.FencingToken, h.ttl)
+	if errors.Is(err, aggregation_data.ErrFencedWrite) {
+		metrics.FencedEventsDiscarded.WithLabelValues(h.Type()).Inc()
+		slog.WarnContext(ctx, "weather event from a former scheduler leader discarded",
+			"id", event.ID, "key", event.Subject, "token", event.FencingToken)
+		return nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Redis Set failed", "error", err)
+		return err
+	}
+	if !applied {
+		metrics.StaleEventsDiscarded.WithLabelValues(h.Type()).Inc()
+		slog.WarnContext(ctx, "stale weather event discarded", "id", event.ID, "key", event.Subject, "time", event.Time)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var StaleEventsDiscarded = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "aggregator_stale_events_discarded_total",
+	Help: "Events skipped because the cache already holds a newer entry.",
+}, []string{"type"})
+
+var FencedEventsDiscarded = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "aggregator_fenced_events_discarded_total",
+	Help: "Events published by a former scheduler leader and not written to the cache.",
+}, []string{"type"})
+
+var EventsHandled = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "aggregator_events_handled_total",
+	Help: "Events passed to handlers, by type and outcome.",
+}, []string{"type", "outcome"})
+
+var EventHandleDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "aggregator_event_handle_duration_seconds",
+	Help:    "Time spent in event handlers.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"type"})
+
+var ConsumerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "aggregator_consumer_lag",
+	Help: "Messages between the committed offset and the high watermark.",
+}, []string{"topic", "partition"})
+
+var ConsumerCommittedOffset = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "aggregator_consumer_committed_offset",
+	Help: "Last committed offset of the consumer group.",
+}, []string{"topic", "partition"})
+
+var ConsumerHighWatermark = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "aggregator_consumer_high_watermark",
+	Help: "High watermark offset of the partition.",
+}, []string{"topic", "partition"})
+
+var MessagesConsumed = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "aggregator_messages_consumed_total",
+	Help: "Messages read from the broker, by topic.",
+}, []string{"topic"})
+
+var SchedulerRefreshDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "aggregator_scheduler_refresh_duration_seconds",
+	Help:    "Time spent refreshing one scheduled item.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"type"})
+
+var SchedulerOverruns = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "aggregator_scheduler_overruns_total",
+	Help: "Refreshes that finished after the item was due again.",
+}, []string{"type"})
+
+var SchedulerInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "aggregator_scheduler_in_flight",
+	Help: "Refreshes currently running in the worker pool.",
+})
+
+var RefreshJobsEnqueued = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "aggregator_refresh_jobs_enqueued_total",
+	Help: "Refresh jobs added to the persistent queue.",
+}, []string{"type"})
+
+var RefreshJobsProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "aggregator_refresh_jobs_processed_total",
+	Help: "Refresh job attempts, by outcome: done, retry or failed.",
+}, []string{"type", "outcome"})
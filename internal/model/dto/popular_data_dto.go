@@ -4,4 +4,20 @@ type PopularDataDto struct {
 	ID       int
 	DataType string
 	Key      string
+	// Priority decides which due items are refreshed first when the
+	// scheduler is at capacity; higher runs first.
+	Priority int
+	// RefreshIntervalSeconds of 0 means the scheduler default.
+	RefreshIntervalSeconds int
+	// CronExpression, in standard five-field form, replaces the refresh
+	// interval when set.
+	CronExpression string
+	// ActiveFrom and ActiveTo ("15:04") limit refreshes to a daily window in
+	// Timezone; the window may cross midnight. Timezone defaults to UTC.
+	ActiveFrom string
+	ActiveTo   string
+	Timezone   string
+	// AutoPromoted items were added by the popularity job and are removed
+	// by it again once they go cold; hand-made items are never demoted.
+	AutoPromoted bool
 }
@@ -0,0 +1,22 @@
+package dto
+
+import "time"
+
+type RefreshJobDto struct {
+	ID       int64
+	DataType string
+	Key      string
+	Priority int
+	// Manual jobs were triggered by an admin and run even while their type
+	// is paused.
+	Manual bool
+	// Token is the fencing token of the scheduler leadership that enqueued
+	// the job, 0 when it was not enqueued by a leader.
+	Token int64
+	// Status is pending or running; finished and failed jobs are deleted.
+	Status string
+	// Attempts counts the claims so far, including the current one.
+	Attempts  int
+	RunAt     time.Time
+	LastError string
+}
@@ -0,0 +1,70 @@
+package events
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	CloudEventsSpecVersion = "1.0"
+	ContentTypeJSON        = "application/json"
+)
+
+type CloudEvent struct {
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	SpecVersion     string          `json:"specversion"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	DataSchema      string          `json:"dataschema,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	// FencingToken is an extension attribute set on events published by the
+	// scheduler leader. Cache writes reject tokens older than the current
+	// leadership; 0 means the event is not fenced.
+	FencingToken int64 `json:"fencingtoken,omitempty"`
+}
+
+func NewCloudEvent(source, eventType, subject string, payload any) (*CloudEvent, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal event payload: %w", err)
+	}
+
+	return &CloudEvent{
+		ID:              NewEventID(),
+		Source:          source,
+		SpecVersion:     CloudEventsSpecVersion,
+		Type:            eventType,
+		Subject:         subject,
+		Time:            time.Now().UTC(),
+		DataContentType: ContentTypeJSON,
+		Data:            data,
+	}, nil
+}
+
+func (e *CloudEvent) Validate() error {
+	switch {
+	case e.SpecVersion != CloudEventsSpecVersion:
+		return fmt.Errorf("unsupported cloudevents specversion: %q", e.SpecVersion)
+	case e.ID == "":
+		return fmt.Errorf("cloudevent id is required")
+	case e.Source == "":
+		return fmt.Errorf("cloudevent source is required")
+	case e.Type == "":
+		return fmt.Errorf("cloudevent type is required")
+	}
+	return nil
+}
+
+// NewEventID returns a random RFC 4122 version 4 UUID.
+func NewEventID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
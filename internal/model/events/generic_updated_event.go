@@ -1,6 +1,12 @@
 package events
 
-import "time"
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 type GenericUpdatedEvent struct {
 	Type      string    `json:"type"`
@@ -8,3 +14,26 @@ type GenericUpdatedEvent struct {
 	Payload   any       `json:"payload"`
 	Timestamp time.Time `json:"timestamp"`
 }
+
+// ToCloudEvent converts a legacy event into the CloudEvents envelope. Legacy
+// events carry no ID, so one is derived from their content to keep it stable
+// across redeliveries.
+func (e *GenericUpdatedEvent) ToCloudEvent(source string) (*CloudEvent, error) {
+	data, err := json.Marshal(e.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal legacy event payload: %w", err)
+	}
+
+	sum := sha1.Sum([]byte(e.Type + "\x00" + e.Key + "\x00" + e.Timestamp.UTC().Format(time.RFC3339Nano)))
+
+	return &CloudEvent{
+		ID:              "legacy-" + hex.EncodeToString(sum[:]),
+		Source:          source,
+		SpecVersion:     CloudEventsSpecVersion,
+		Type:            e.Type,
+		Subject:         e.Key,
+		Time:            e.Timestamp.UTC(),
+		DataContentType: ContentTypeJSON,
+		Data:            data,
+	}, nil
+}
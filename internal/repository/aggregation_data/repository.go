@@ -2,18 +2,60 @@ package aggregation_data
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+const timestampSuffix = ":updated_at"
+
+var (
+	ErrFencedWrite      = errors.New("write fenced off by a newer scheduler leadership")
+	ErrMissingTimestamp = errors.New("update time is required")
+)
+
+// setIfNewerScript writes the value only when the stored timestamp is not
+// newer than the incoming one and the fencing token, when given, is not older
+// than the current leadership's. KEYS: value key, timestamp key, fencing
+// token key. ARGV: value, timestamp in microseconds, ttl in milliseconds
+// (0 = no expiry), fencing token (0 = not fenced).
+// Returns 1 when written, 0 when stale and -1 when fenced.
+var setIfNewerScript = redis.NewScript(`
+local token = tonumber(ARGV[4])
+if token > 0 then
+	local leader = redis.call('GET', KEYS[3])
+	if leader and tonumber(leader) > token then
+		return -1
+	end
+end
+local current = redis.call('GET', KEYS[2])
+if current and tonumber(current) > tonumber(ARGV[2]) then
+	return 0
+end
+local ttl = tonumber(ARGV[3])
+if ttl > 0 then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ttl)
+	redis.call('SET', KEYS[2], ARGV[2], 'PX', ttl)
+else
+	redis.call('SET', KEYS[1], ARGV[1])
+	redis.call('SET', KEYS[2], ARGV[2])
+end
+return 1
+`)
+
 type RedisRepository struct {
 	redisClient *redis.Client
+	fencingKey  string
 }
 
-func NewRedisRepository(client *redis.Client) *RedisRepository {
+// NewRedisRepository creates a cache repository. fencingKey names the
+// counter of the scheduler leader's fencing tokens; with an empty key writes
+// are never fenced, e.g. when rebuilding the cache from old events.
+func NewRedisRepository(client *redis.Client, fencingKey string) *RedisRepository {
 	return &RedisRepository{
 		redisClient: client,
+		fencingKey:  fencingKey,
 	}
 }
 
@@ -21,6 +63,42 @@ func (r *RedisRepository) Set(ctx context.Context, key string, value string, ttl
 	return r.redisClient.Set(ctx, key, value, ttl).Err()
 }
 
+// SetIfNewer writes value unless the cache holds a newer one. A write with a
+// fencing token older than the current leader's fails with ErrFencedWrite.
+// updatedAt must be set: a zero time would compare as older than any cached
+// value.
+func (r *RedisRepository) SetIfNewer(ctx context.Context, key string, value string, updatedAt time.Time, token int64, ttl time.Duration) (bool, error) {
+	if updatedAt.IsZero() {
+		return false, ErrMissingTimestamp
+	}
+
+	fencingKey := r.fencingKey
+	if fencingKey == "" {
+		token = 0
+		fencingKey = key + timestampSuffix
+	}
+
+	res, err := setIfNewerScript.Run(ctx, r.redisClient,
+		[]string{key, key + timestampSuffix, fencingKey},
+		value, updatedAt.UnixMicro(), ttl.Milliseconds(), token,
+	).Int()
+	if err != nil {
+		return false, err
+	}
+	if res < 0 {
+		return false, ErrFencedWrite
+	}
+	return res == 1, nil
+}
+
+func (r *RedisRepository) Exists(ctx context.Context, key string) (bool, error) {
+	n, err := r.redisClient.Exists(ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
 func (r *RedisRepository) Get(ctx context.Context, key string) (string, error) {
 	return r.redisClient.Get(ctx, key).Result()
 }
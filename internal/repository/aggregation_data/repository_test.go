@@ -0,0 +1,84 @@
+package aggregation_data_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"service-info-aggregator/internal/repository/aggregation_data"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fencingKey = "scheduler:leader:token"
+
+func newTestRepository(t *testing.T) (*miniredis.Miniredis, *aggregation_data.RedisRepository) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return server, aggregation_data.NewRedisRepository(client, fencingKey)
+}
+
+func TestRedisRepository_SetIfNewer_RefusesStaleFencingToken(t *testing.T) {
+	server, repo := newTestRepository(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	require.NoError(t, server.Set(fencingKey, "5"))
+
+	applied, err := repo.SetIfNewer(ctx, "weather:Moscow", `{"temp":1}`, now, 4, time.Minute)
+	assert.ErrorIs(t, err, aggregation_data.ErrFencedWrite)
+	assert.False(t, applied)
+	assert.False(t, server.Exists("weather:Moscow"))
+
+	applied, err = repo.SetIfNewer(ctx, "weather:Moscow", `{"temp":2}`, now, 5, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, applied)
+
+	// Unfenced writes, e.g. on-demand fetches, are never refused.
+	applied, err = repo.SetIfNewer(ctx, "weather:Moscow", `{"temp":3}`, now.Add(time.Second), 0, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, applied)
+
+	value, err := repo.Get(ctx, "weather:Moscow")
+	require.NoError(t, err)
+	assert.Equal(t, `{"temp":3}`, value)
+}
+
+func TestRedisRepository_SetIfNewer_ComparesTimestamps(t *testing.T) {
+	_, repo := newTestRepository(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	applied, err := repo.SetIfNewer(ctx, "weather:Moscow", `{"temp":1}`, now, 0, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, applied)
+
+	applied, err = repo.SetIfNewer(ctx, "weather:Moscow", `{"temp":2}`, now.Add(time.Second), 0, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, applied, "newer value")
+
+	applied, err = repo.SetIfNewer(ctx, "weather:Moscow", `{"temp":3}`, now, 0, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, applied, "older value")
+
+	applied, err = repo.SetIfNewer(ctx, "weather:Moscow", `{"temp":4}`, now.Add(time.Second), 0, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, applied, "value with an equal time")
+
+	value, err := repo.Get(ctx, "weather:Moscow")
+	require.NoError(t, err)
+	assert.Equal(t, `{"temp":4}`, value)
+}
+
+func TestRedisRepository_SetIfNewer_RejectsZeroTime(t *testing.T) {
+	server, repo := newTestRepository(t)
+
+	applied, err := repo.SetIfNewer(context.Background(), "weather:Moscow", `{"temp":1}`, time.Time{}, 0, time.Minute)
+	assert.ErrorIs(t, err, aggregation_data.ErrMissingTimestamp)
+	assert.False(t, applied)
+	assert.False(t, server.Exists("weather:Moscow"))
+}
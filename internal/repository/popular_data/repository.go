@@ -3,7 +3,9 @@ package popular_data
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"service-info-aggregator/internal/model/dto"
@@ -17,6 +19,24 @@ type Repository interface {
 	Delete(ctx context.Context, id int) error
 }
 
+// ChangesChannel is notified by a trigger on every change of popular_data.
+const ChangesChannel = "popular_data_changes"
+
+// Change is the payload of a ChangesChannel notification; Op is INSERT,
+// UPDATE or DELETE.
+type Change struct {
+	Op string `json:"op"`
+	ID int    `json:"id"`
+}
+
+func ParseChange(payload string) (Change, error) {
+	var change Change
+	if err := json.Unmarshal([]byte(payload), &change); err != nil {
+		return Change{}, fmt.Errorf("invalid popular_data change %q: %w", payload, err)
+	}
+	return change, nil
+}
+
 type PopularDataRepository struct {
 	db *sql.DB
 }
@@ -29,22 +49,26 @@ func NewPopularDataRepository(db *sql.DB) *PopularDataRepository {
 
 func (r *PopularDataRepository) Create(ctx context.Context, inputData *dto.PopularDataDto) (*dto.PopularDataDto, error) {
 	query := `
-		INSERT INTO popular_data (data_type, key, created_at, updated_at)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, data_type, key
+		INSERT INTO popular_data (data_type, key, priority, refresh_interval_seconds,
+			cron_expression, active_from, active_to, timezone, auto_promoted, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, data_type, key, priority, refresh_interval_seconds, cron_expression, active_from, active_to, timezone, auto_promoted
 	`
 
 	var created dto.PopularDataDto
 	err := r.db.QueryRowContext(ctx, query,
 		inputData.DataType,
 		inputData.Key,
+		inputData.Priority,
+		inputData.RefreshIntervalSeconds,
+		inputData.CronExpression,
+		inputData.ActiveFrom,
+		inputData.ActiveTo,
+		inputData.Timezone,
+		inputData.AutoPromoted,
 		time.Now(),
 		time.Now(),
-	).Scan(
-		&created.ID,
-		&created.DataType,
-		&created.Key,
-	)
+	).Scan(scanTargets(&created)...)
 	if err != nil {
 		return nil, err
 	}
@@ -53,7 +77,7 @@ func (r *PopularDataRepository) Create(ctx context.Context, inputData *dto.Popul
 }
 
 func (r *PopularDataRepository) GetAll(ctx context.Context) ([]dto.PopularDataDto, error) {
-	query := `SELECT data_type, key FROM popular_data`
+	query := `SELECT id, data_type, key, priority, refresh_interval_seconds, cron_expression, active_from, active_to, timezone, auto_promoted FROM popular_data`
 
 	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
@@ -64,7 +88,7 @@ func (r *PopularDataRepository) GetAll(ctx context.Context) ([]dto.PopularDataDt
 	results := make([]dto.PopularDataDto, 0)
 	for rows.Next() {
 		var popularDataDto dto.PopularDataDto
-		if err := rows.Scan(&popularDataDto.DataType, &popularDataDto.Key); err != nil {
+		if err := rows.Scan(scanTargets(&popularDataDto)...); err != nil {
 			return nil, err
 		}
 		results = append(results, popularDataDto)
@@ -74,12 +98,12 @@ func (r *PopularDataRepository) GetAll(ctx context.Context) ([]dto.PopularDataDt
 }
 
 func (r *PopularDataRepository) GetById(ctx context.Context, id int) (*dto.PopularDataDto, error) {
-	query := `SELECT data_type, key FROM popular_data WHERE id = $1`
+	query := `SELECT id, data_type, key, priority, refresh_interval_seconds, cron_expression, active_from, active_to, timezone, auto_promoted FROM popular_data WHERE id = $1`
 
 	var result dto.PopularDataDto
 
 	row := r.db.QueryRowContext(ctx, query, id)
-	err := row.Scan(&result.DataType, &result.Key)
+	err := row.Scan(scanTargets(&result)...)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
@@ -87,14 +111,21 @@ func (r *PopularDataRepository) GetById(ctx context.Context, id int) (*dto.Popul
 	return &result, err
 }
 
+// Update replaces an item by hand. The item stops being auto-promoted, so the
+// promoter no longer demotes it.
 func (r *PopularDataRepository) Update(ctx context.Context, id int, inputData *dto.PopularDataDto) (*dto.PopularDataDto, error) {
 	query := `UPDATE popular_data 
-			  SET data_type = $1, key = $2, updated_at = $3 
-			  WHERE id = $4
-			  RETURNING id, data_type, key`
+			  SET data_type = $1, key = $2, priority = $3, refresh_interval_seconds = $4,
+			      cron_expression = $5, active_from = $6, active_to = $7, timezone = $8, updated_at = $9,
+			      auto_promoted = false
+			  WHERE id = $10
+			  RETURNING id, data_type, key, priority, refresh_interval_seconds, cron_expression, active_from, active_to, timezone, auto_promoted`
 
 	var updated dto.PopularDataDto
-	err := r.db.QueryRowContext(ctx, query, inputData.DataType, inputData.Key, time.Now(), id).Scan(&updated.ID, &updated.DataType, &updated.Key)
+	err := r.db.QueryRowContext(ctx, query, inputData.DataType, inputData.Key, inputData.Priority,
+		inputData.RefreshIntervalSeconds, inputData.CronExpression, inputData.ActiveFrom, inputData.ActiveTo,
+		inputData.Timezone, time.Now(), id).
+		Scan(scanTargets(&updated)...)
 	if err != nil {
 		return nil, err
 	}
@@ -106,3 +137,9 @@ func (r *PopularDataRepository) Delete(ctx context.Context, id int) error {
 	_, err := r.db.ExecContext(ctx, query, id)
 	return err
 }
+
+// scanTargets lists the fields in the column order of every query above.
+func scanTargets(d *dto.PopularDataDto) []any {
+	return []any{&d.ID, &d.DataType, &d.Key, &d.Priority, &d.RefreshIntervalSeconds,
+		&d.CronExpression, &d.ActiveFrom, &d.ActiveTo, &d.Timezone, &d.AutoPromoted}
+}
@@ -12,6 +12,9 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+var columns = []string{"id", "data_type", "key", "priority", "refresh_interval_seconds",
+	"cron_expression", "active_from", "active_to", "timezone", "auto_promoted"}
+
 func TestPopularDataRepository_Create_Success(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -24,9 +27,10 @@ func TestPopularDataRepository_Create_Success(t *testing.T) {
 		Key:      "Moscow",
 	}
 
-	rows := sqlmock.NewRows([]string{"id", "data_type", "key"}).AddRow(1, "weather", "Moscow")
+	rows := sqlmock.NewRows(columns).
+		AddRow(1, "weather", "Moscow", 0, 0, "", "", "", "", false)
 	mock.ExpectQuery("INSERT INTO popular_data").
-		WithArgs(input.DataType, input.Key, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(input.DataType, input.Key, 0, 0, "", "", "", "", false, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnRows(rows)
 	result, err := repo.Create(context.Background(), input)
 
@@ -45,13 +49,17 @@ func TestPopularDataRepository_GetAll_Success(t *testing.T) {
 	defer db.Close()
 
 	repo := popular_data.NewPopularDataRepository(db)
-	rows := mock.NewRows([]string{"data_type", "key"}).AddRow("weather", "Moscow")
-	mock.ExpectQuery("SELECT data_type, key FROM popular_data").WillReturnRows(rows)
+	rows := mock.NewRows(columns).
+		AddRow(3, "weather", "Moscow", 10, 60, "", "", "", "", false)
+	mock.ExpectQuery("SELECT id, data_type, key, priority, refresh_interval_seconds, cron_expression, active_from, active_to, timezone, auto_promoted FROM popular_data").WillReturnRows(rows)
 	result, err := repo.GetAll(context.Background())
 
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	assert.Equal(t, 1, len(result))
+	assert.Equal(t, 3, result[0].ID)
+	assert.Equal(t, 10, result[0].Priority)
+	assert.Equal(t, 60, result[0].RefreshIntervalSeconds)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -61,13 +69,14 @@ func TestPopularDataRepository_GetById_Success(t *testing.T) {
 	defer db.Close()
 
 	repo := popular_data.NewPopularDataRepository(db)
-	rows := mock.NewRows([]string{"data_type", "key"}).AddRow("weather", "Novosibirsk")
-	mock.ExpectQuery("SELECT data_type, key FROM popular_data").WillReturnRows(rows)
-	result, err := repo.GetById(context.Background(), 0)
+	rows := mock.NewRows(columns).
+		AddRow(7, "weather", "Novosibirsk", 0, 0, "", "", "", "", false)
+	mock.ExpectQuery("SELECT id, data_type, key, priority, refresh_interval_seconds, cron_expression, active_from, active_to, timezone, auto_promoted FROM popular_data").WillReturnRows(rows)
+	result, err := repo.GetById(context.Background(), 7)
 
 	require.NoError(t, err)
 	require.NotNil(t, result)
-	assert.Equal(t, 0, result.ID)
+	assert.Equal(t, 7, result.ID)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -81,14 +90,26 @@ func TestPopularDataRepository_Update_Success(t *testing.T) {
 		DataType: "weather",
 		Key:      "Berlin",
 	}
-	rows := mock.NewRows([]string{"id", "data_type", "key"}).AddRow(1, "weather", "Berlin")
-	mock.ExpectQuery("UPDATE popular_data SET").WillReturnRows(rows)
+	rows := mock.NewRows(columns).
+		AddRow(1, "weather", "Berlin", 0, 0, "", "", "", "", false)
+	mock.ExpectQuery(`UPDATE popular_data SET .* auto_promoted = false`).WillReturnRows(rows)
 	result, err := repo.Update(context.Background(), 1, input)
 
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	assert.Equal(t, 1, result.ID)
+	assert.False(t, result.AutoPromoted)
 	assert.Equal(t, "weather", result.DataType)
 	assert.Equal(t, "Berlin", result.Key)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestParseChange(t *testing.T) {
+	change, err := popular_data.ParseChange(`{"op":"UPDATE","id":12}`)
+
+	require.NoError(t, err)
+	assert.Equal(t, popular_data.Change{Op: "UPDATE", ID: 12}, change)
+
+	_, err = popular_data.ParseChange("not json")
+	assert.Error(t, err)
+}
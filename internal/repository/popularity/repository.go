@@ -0,0 +1,120 @@
+package popularity
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	bucketPrefix = "popularity:requests:"
+	scoresKey    = "popularity:scores"
+	promoteLock  = "popularity:promote:lock"
+)
+
+type Score struct {
+	DataType string  `json:"data_type"`
+	Key      string  `json:"key"`
+	Score    float64 `json:"score"`
+}
+
+// RedisRepository counts requests per (type, key) in one sorted set per time
+// bucket. Scores are the bucket counts summed with weight decay^age, so a key
+// nobody asks for any more fades out over a few buckets.
+type RedisRepository struct {
+	client         *redis.Client
+	bucketDuration time.Duration
+	buckets        int
+	decay          float64
+}
+
+func NewRedisRepository(client *redis.Client, bucketDuration time.Duration, buckets int, decay float64) *RedisRepository {
+	return &RedisRepository{
+		client:         client,
+		bucketDuration: bucketDuration,
+		buckets:        buckets,
+		decay:          decay,
+	}
+}
+
+func (r *RedisRepository) Record(ctx context.Context, dataType, key string) error {
+	bucket := r.bucketKey(r.currentBucket())
+
+	pipe := r.client.Pipeline()
+	pipe.ZIncrBy(ctx, bucket, 1, member(dataType, key))
+	pipe.Expire(ctx, bucket, time.Duration(r.buckets+1)*r.bucketDuration)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// UpdateScores recomputes the decayed scores read by Top and ScoresOf from
+// every bucket. The promoter runs it once per round.
+func (r *RedisRepository) UpdateScores(ctx context.Context) error {
+	current := r.currentBucket()
+	keys := make([]string, r.buckets)
+	weights := make([]float64, r.buckets)
+	for age := range r.buckets {
+		keys[age] = r.bucketKey(current - int64(age))
+		weights[age] = math.Pow(r.decay, float64(age))
+	}
+	return r.client.ZUnionStore(ctx, scoresKey, &redis.ZStore{Keys: keys, Weights: weights, Aggregate: "SUM"}).Err()
+}
+
+// Top returns the n keys with the highest decayed score as of the last
+// UpdateScores.
+func (r *RedisRepository) Top(ctx context.Context, n int) ([]Score, error) {
+	top, err := r.client.ZRevRangeWithScores(ctx, scoresKey, 0, int64(n-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make([]Score, 0, len(top))
+	for _, z := range top {
+		dataType, key, _ := strings.Cut(z.Member.(string), ":")
+		scores = append(scores, Score{DataType: dataType, Key: key, Score: z.Score})
+	}
+	return scores, nil
+}
+
+// ScoresOf returns the decayed score of every given key as of the last
+// UpdateScores, 0 for keys without recent requests. Keys are "type:key".
+func (r *RedisRepository) ScoresOf(ctx context.Context, keys []string) (map[string]float64, error) {
+	if len(keys) == 0 {
+		return map[string]float64{}, nil
+	}
+
+	values, err := r.client.ZMScore(ctx, scoresKey, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make(map[string]float64, len(keys))
+	for i, v := range values {
+		scores[keys[i]] = v
+	}
+	return scores, nil
+}
+
+// TryLock lets one replica run the promotion job per round. Rounds are the
+// interval-long slots since the epoch, so replicas whose tickers are out of
+// phase still agree on the round and only one of them runs it.
+func (r *RedisRepository) TryLock(ctx context.Context, owner string, interval time.Duration) (bool, error) {
+	round := time.Now().UnixNano() / int64(interval)
+	return r.client.SetNX(ctx, fmt.Sprintf("%s:%d", promoteLock, round), owner, 2*interval).Result()
+}
+
+func (r *RedisRepository) currentBucket() int64 {
+	return time.Now().UnixNano() / int64(r.bucketDuration)
+}
+
+func (r *RedisRepository) bucketKey(bucket int64) string {
+	return fmt.Sprintf("%s%d", bucketPrefix, bucket)
+}
+
+func member(dataType, key string) string {
+	return dataType + ":" + key
+}
@@ -0,0 +1,62 @@
+package popularity_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"service-info-aggregator/internal/repository/popularity"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisRepository_TopAndScores(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	repo := popularity.NewRedisRepository(client, time.Hour, 6, 0.5)
+	ctx := context.Background()
+
+	for range 3 {
+		require.NoError(t, repo.Record(ctx, "weather", "Moscow"))
+	}
+	require.NoError(t, repo.Record(ctx, "weather", "Berlin"))
+
+	top, err := repo.Top(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, top, "scores are computed by UpdateScores")
+
+	require.NoError(t, repo.UpdateScores(ctx))
+	top, err = repo.Top(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []popularity.Score{{DataType: "weather", Key: "Moscow", Score: 3}}, top)
+
+	scores, err := repo.ScoresOf(ctx, []string{"weather:Berlin", "weather:Paris"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"weather:Berlin": 1, "weather:Paris": 0}, scores)
+}
+
+func TestRedisRepository_TryLockOncePerRound(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	repo := popularity.NewRedisRepository(client, time.Hour, 6, 0.5)
+	ctx := context.Background()
+	// Long enough for both calls to fall into one round.
+	const interval = 24 * time.Hour
+
+	locked, err := repo.TryLock(ctx, "a", interval)
+	require.NoError(t, err)
+	assert.True(t, locked)
+
+	// Half an interval later the lock of a TTL-based lock would be gone.
+	server.FastForward(interval / 2)
+	locked, err = repo.TryLock(ctx, "b", interval)
+	require.NoError(t, err)
+	assert.False(t, locked)
+}
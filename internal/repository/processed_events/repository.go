@@ -0,0 +1,41 @@
+package processed_events
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	keyPrefix    = "events:processed:"
+	claimedValue = "in-progress"
+)
+
+type RedisRepository struct {
+	redisClient *redis.Client
+}
+
+func NewRedisRepository(client *redis.Client) *RedisRepository {
+	return &RedisRepository{
+		redisClient: client,
+	}
+}
+
+// Claim marks the event as being handled for ttl and reports whether this
+// call did so. It returns false when the event is being handled or was
+// handled already. A claim that is not confirmed expires after ttl, so an
+// event whose handling was cut short by a crash is handled again.
+func (r *RedisRepository) Claim(ctx context.Context, eventID string, ttl time.Duration) (bool, error) {
+	return r.redisClient.SetNX(ctx, keyPrefix+eventID, claimedValue, ttl).Result()
+}
+
+// Confirm marks a claimed event as handled for ttl.
+func (r *RedisRepository) Confirm(ctx context.Context, eventID string, ttl time.Duration) error {
+	return r.redisClient.Set(ctx, keyPrefix+eventID, time.Now().UTC().Format(time.RFC3339), ttl).Err()
+}
+
+// Release drops a claim so that a redelivery of the event is handled again.
+func (r *RedisRepository) Release(ctx context.Context, eventID string) error {
+	return r.redisClient.Del(ctx, keyPrefix+eventID).Err()
+}
@@ -0,0 +1,83 @@
+package processed_events_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"service-info-aggregator/internal/repository/processed_events"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRepository(t *testing.T) (*processed_events.RedisRepository, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return processed_events.NewRedisRepository(client), server
+}
+
+func TestRedisRepository_ClaimOnce(t *testing.T) {
+	repo, server := newTestRepository(t)
+	ctx := context.Background()
+
+	claimed, err := repo.Claim(ctx, "event-1", time.Hour)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	claimed, err = repo.Claim(ctx, "event-1", time.Hour)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+
+	claimed, err = repo.Claim(ctx, "event-2", time.Hour)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	server.FastForward(time.Hour)
+	claimed, err = repo.Claim(ctx, "event-1", time.Hour)
+	require.NoError(t, err)
+	assert.True(t, claimed, "claims expire after the TTL")
+}
+
+func TestRedisRepository_Release(t *testing.T) {
+	repo, _ := newTestRepository(t)
+	ctx := context.Background()
+
+	_, err := repo.Claim(ctx, "event-1", time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, repo.Release(ctx, "event-1"))
+
+	claimed, err := repo.Claim(ctx, "event-1", time.Hour)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+}
+
+func TestRedisRepository_ConfirmOutlivesClaim(t *testing.T) {
+	repo, server := newTestRepository(t)
+	ctx := context.Background()
+
+	_, err := repo.Claim(ctx, "event-1", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, repo.Confirm(ctx, "event-1", time.Hour))
+
+	server.FastForward(30 * time.Minute)
+	claimed, err := repo.Claim(ctx, "event-1", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+}
+
+func TestRedisRepository_AbandonedClaimExpires(t *testing.T) {
+	repo, server := newTestRepository(t)
+	ctx := context.Background()
+
+	_, err := repo.Claim(ctx, "event-1", time.Minute)
+	require.NoError(t, err)
+
+	server.FastForward(time.Minute)
+	claimed, err := repo.Claim(ctx, "event-1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+}
@@ -0,0 +1,154 @@
+package refresh_jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"service-info-aggregator/internal/model/dto"
+
+	"github.com/lib/pq"
+)
+
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+)
+
+// ClaimFilter keeps jobs out of a claim. Full types are never claimed; paused
+// types, or all types when AllPaused is set, only by manual jobs.
+type ClaimFilter struct {
+	FullTypes   []string
+	PausedTypes []string
+	AllPaused   bool
+}
+
+type Repository interface {
+	Enqueue(ctx context.Context, job *dto.RefreshJobDto) (bool, error)
+	Claim(ctx context.Context, owner string, limit int, visibility time.Duration, filter ClaimFilter) ([]dto.RefreshJobDto, error)
+	Release(ctx context.Context, id int64, owner string) error
+	Complete(ctx context.Context, id int64, owner string) error
+	Retry(ctx context.Context, id int64, owner string, runAt time.Time, cause string) error
+	Fail(ctx context.Context, id int64, owner string) error
+}
+
+type RefreshJobRepository struct {
+	db *sql.DB
+}
+
+func NewRefreshJobRepository(db *sql.DB) *RefreshJobRepository {
+	return &RefreshJobRepository{
+		db: db,
+	}
+}
+
+// Enqueue adds a pending job unless the key already has an outstanding one,
+// and reports whether it did. A manual job marks the outstanding one as
+// manual instead, so it runs even while its type is paused, and a newer
+// leader's job hands the outstanding one its fencing token.
+func (r *RefreshJobRepository) Enqueue(ctx context.Context, job *dto.RefreshJobDto) (bool, error) {
+	query := `
+		INSERT INTO refresh_jobs (data_type, key, priority, run_at, manual, fencing_token)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (data_type, key) WHERE status IN ('pending', 'running')
+		DO UPDATE SET manual = refresh_jobs.manual OR $5,
+		              fencing_token = GREATEST(refresh_jobs.fencing_token, $6)
+		WHERE $5 OR refresh_jobs.fencing_token < $6
+		RETURNING xmax = 0
+	`
+
+	runAt := job.RunAt
+	if runAt.IsZero() {
+		runAt = time.Now()
+	}
+
+	var inserted bool
+	err := r.db.QueryRowContext(ctx, query, job.DataType, job.Key, job.Priority, runAt, job.Manual, job.Token).Scan(&inserted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return inserted, err
+}
+
+// Claim locks up to limit due jobs for owner until the visibility timeout
+// passes. Jobs whose previous owner let the timeout pass are claimed again.
+// SKIP LOCKED lets concurrent claims pass each other without waiting or
+// taking the same job.
+func (r *RefreshJobRepository) Claim(ctx context.Context, owner string, limit int, visibility time.Duration, filter ClaimFilter) ([]dto.RefreshJobDto, error) {
+	query := `
+		UPDATE refresh_jobs
+		SET status = 'running', attempts = attempts + 1, locked_by = $1,
+		    locked_until = now() + $2::float8 * interval '1 millisecond', updated_at = now()
+		WHERE id IN (
+			SELECT id FROM refresh_jobs
+			WHERE ((status = 'pending' AND run_at <= now())
+			    OR (status = 'running' AND locked_until < now()))
+			  AND NOT data_type = ANY($4)
+			  AND (manual OR (NOT $6 AND NOT data_type = ANY($5)))
+			ORDER BY priority DESC, run_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, data_type, key, priority, manual, fencing_token, status, attempts, run_at, last_error
+	`
+
+	// A nil slice would be sent as NULL, which matches no job at all.
+	rows, err := r.db.QueryContext(ctx, query, owner, visibility.Milliseconds(), limit,
+		pq.Array(append([]string{}, filter.FullTypes...)), pq.Array(append([]string{}, filter.PausedTypes...)),
+		filter.AllPaused)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := make([]dto.RefreshJobDto, 0, limit)
+	for rows.Next() {
+		var job dto.RefreshJobDto
+		if err := rows.Scan(&job.ID, &job.DataType, &job.Key, &job.Priority, &job.Manual, &job.Token, &job.Status,
+			&job.Attempts, &job.RunAt, &job.LastError); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// Release hands a claimed job back without counting the attempt, e.g. when
+// the claim returned more jobs of a provider than it may run at once.
+func (r *RefreshJobRepository) Release(ctx context.Context, id int64, owner string) error {
+	query := `
+		UPDATE refresh_jobs
+		SET status = 'pending', attempts = attempts - 1, locked_by = NULL, locked_until = NULL, updated_at = now()
+		WHERE id = $1 AND locked_by = $2 AND status = 'running'
+	`
+	_, err := r.db.ExecContext(ctx, query, id, owner)
+	return err
+}
+
+// Complete removes a finished job. It is a no-op when owner lost the job to
+// another worker after its visibility timeout.
+func (r *RefreshJobRepository) Complete(ctx context.Context, id int64, owner string) error {
+	query := `DELETE FROM refresh_jobs WHERE id = $1 AND locked_by = $2 AND status = 'running'`
+	_, err := r.db.ExecContext(ctx, query, id, owner)
+	return err
+}
+
+// Retry returns a job to the queue to be claimed again at runAt.
+func (r *RefreshJobRepository) Retry(ctx context.Context, id int64, owner string, runAt time.Time, cause string) error {
+	query := `
+		UPDATE refresh_jobs
+		SET status = 'pending', run_at = $3, last_error = $4, locked_by = NULL, locked_until = NULL, updated_at = now()
+		WHERE id = $1 AND locked_by = $2 AND status = 'running'
+	`
+	_, err := r.db.ExecContext(ctx, query, id, owner, runAt, cause)
+	return err
+}
+
+// Fail removes a job that ran out of attempts. The worker records the cause
+// in the run history and logs, so failed jobs do not pile up in the table.
+func (r *RefreshJobRepository) Fail(ctx context.Context, id int64, owner string) error {
+	query := `DELETE FROM refresh_jobs WHERE id = $1 AND locked_by = $2 AND status = 'running'`
+	_, err := r.db.ExecContext(ctx, query, id, owner)
+	return err
+}
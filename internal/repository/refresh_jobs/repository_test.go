@@ -0,0 +1,82 @@
+package refresh_jobs_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"service-info-aggregator/internal/model/dto"
+	"service-info-aggregator/internal/repository/refresh_jobs"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshJobRepository_Enqueue_SkipsOutstandingKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := refresh_jobs.NewRefreshJobRepository(db)
+
+	mock.ExpectQuery("INSERT INTO refresh_jobs .* ON CONFLICT").
+		WithArgs("weather", "Moscow", 3, sqlmock.AnyArg(), false, int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"inserted"}).AddRow(true))
+	mock.ExpectQuery("INSERT INTO refresh_jobs .* ON CONFLICT").
+		WithArgs("weather", "Moscow", 3, sqlmock.AnyArg(), false, int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"inserted"}))
+
+	job := &dto.RefreshJobDto{DataType: "weather", Key: "Moscow", Priority: 3, Token: 4}
+	added, err := repo.Enqueue(context.Background(), job)
+	require.NoError(t, err)
+	assert.True(t, added)
+
+	added, err = repo.Enqueue(context.Background(), job)
+	require.NoError(t, err)
+	assert.False(t, added)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefreshJobRepository_Claim(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := refresh_jobs.NewRefreshJobRepository(db)
+	runAt := time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "data_type", "key", "priority", "manual", "fencing_token", "status", "attempts", "run_at", "last_error"}).
+		AddRow(7, "weather", "Moscow", 5, true, 2, "running", 1, runAt, "").
+		AddRow(8, "weather", "Berlin", 0, false, 0, "running", 3, runAt, "timeout")
+	mock.ExpectQuery("UPDATE refresh_jobs .* FOR UPDATE SKIP LOCKED").
+		WithArgs("replica-1", int64(60000), 2, "{}", `{"news"}`, false).
+		WillReturnRows(rows)
+
+	jobs, err := repo.Claim(context.Background(), "replica-1", 2, time.Minute,
+		refresh_jobs.ClaimFilter{PausedTypes: []string{"news"}})
+
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, int64(7), jobs[0].ID)
+	assert.True(t, jobs[0].Manual)
+	assert.Equal(t, int64(2), jobs[0].Token)
+	assert.Equal(t, 3, jobs[1].Attempts)
+	assert.Equal(t, "timeout", jobs[1].LastError)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefreshJobRepository_Fail_DeletesTheJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := refresh_jobs.NewRefreshJobRepository(db)
+
+	mock.ExpectExec("DELETE FROM refresh_jobs WHERE id = .* AND locked_by = .* AND status = 'running'").
+		WithArgs(int64(7), "replica-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, repo.Fail(context.Background(), 7, "replica-1"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,54 @@
+package scheduler_pauses
+
+import (
+	"context"
+	"slices"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// allTypes is the set member pausing every data type.
+const allTypes = "*"
+
+// RedisRepository keeps the paused data types in a Redis set shared by all
+// replicas, so a pause survives restarts and leader failover.
+type RedisRepository struct {
+	client *redis.Client
+	key    string
+}
+
+func NewRedisRepository(client *redis.Client, key string) *RedisRepository {
+	return &RedisRepository{
+		client: client,
+		key:    key,
+	}
+}
+
+// Pause pauses one data type, or all of them when dataType is empty.
+func (r *RedisRepository) Pause(ctx context.Context, dataType string) error {
+	if dataType == "" {
+		dataType = allTypes
+	}
+	return r.client.SAdd(ctx, r.key, dataType).Err()
+}
+
+// Resume lifts the pause of one data type, or every pause when dataType is
+// empty.
+func (r *RedisRepository) Resume(ctx context.Context, dataType string) error {
+	if dataType == "" {
+		return r.client.Del(ctx, r.key).Err()
+	}
+	return r.client.SRem(ctx, r.key, dataType).Err()
+}
+
+func (r *RedisRepository) Paused(ctx context.Context) (bool, []string, error) {
+	members, err := r.client.SMembers(ctx, r.key).Result()
+	if err != nil {
+		return false, nil, err
+	}
+
+	all := slices.Contains(members, allTypes)
+	types := slices.DeleteFunc(members, func(m string) bool { return m == allTypes })
+	slices.Sort(types)
+	return all, types, nil
+}
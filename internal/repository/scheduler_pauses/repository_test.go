@@ -0,0 +1,42 @@
+package scheduler_pauses_test
+
+import (
+	"context"
+	"testing"
+
+	"service-info-aggregator/internal/repository/scheduler_pauses"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisRepository_PauseAndResume(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	repo := scheduler_pauses.NewRedisRepository(client, "scheduler:paused")
+	ctx := context.Background()
+
+	require.NoError(t, repo.Pause(ctx, "weather"))
+	require.NoError(t, repo.Pause(ctx, "news"))
+	require.NoError(t, repo.Pause(ctx, ""))
+
+	all, types, err := repo.Paused(ctx)
+	require.NoError(t, err)
+	assert.True(t, all)
+	assert.Equal(t, []string{"news", "weather"}, types)
+
+	require.NoError(t, repo.Resume(ctx, "news"))
+	_, types, err = repo.Paused(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"weather"}, types)
+
+	require.NoError(t, repo.Resume(ctx, ""))
+	all, types, err = repo.Paused(ctx)
+	require.NoError(t, err)
+	assert.False(t, all)
+	assert.Empty(t, types)
+}
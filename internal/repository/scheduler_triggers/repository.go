@@ -0,0 +1,73 @@
+package scheduler_triggers
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	idField = "id"
+	// maxLen bounds the stream; schedulers read it every second, so only the
+	// latest triggers are ever needed.
+	maxLen = 1000
+)
+
+// RedisRepository keeps manual triggers in a Redis stream every replica
+// reads, so a trigger reaches the replica running the scheduler, or the one
+// owning the item, whichever replica received it.
+type RedisRepository struct {
+	client *redis.Client
+	key    string
+}
+
+func NewRedisRepository(client *redis.Client, key string) *RedisRepository {
+	return &RedisRepository{
+		client: client,
+		key:    key,
+	}
+}
+
+// Trigger adds a trigger of one item, or of every item when id is 0.
+func (r *RedisRepository) Trigger(ctx context.Context, id int) error {
+	return r.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: r.key,
+		MaxLen: maxLen,
+		Approx: true,
+		Values: map[string]any{idField: id},
+	}).Err()
+}
+
+// Since returns the item IDs triggered after cursor and the cursor to pass
+// next. An empty cursor returns no IDs, only the cursor of the latest
+// trigger.
+func (r *RedisRepository) Since(ctx context.Context, cursor string) ([]int, string, error) {
+	if cursor == "" {
+		latest, err := r.client.XRevRangeN(ctx, r.key, "+", "-", 1).Result()
+		if err != nil {
+			return nil, "", err
+		}
+		if len(latest) == 0 {
+			return nil, "0-0", nil
+		}
+		return nil, latest[0].ID, nil
+	}
+
+	entries, err := r.client.XRangeN(ctx, r.key, "("+cursor, "+", maxLen).Result()
+	if err != nil {
+		return nil, cursor, err
+	}
+
+	ids := make([]int, 0, len(entries))
+	for _, e := range entries {
+		cursor = e.ID
+		raw, _ := e.Values[idField].(string)
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, cursor, nil
+}
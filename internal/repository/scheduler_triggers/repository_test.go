@@ -0,0 +1,49 @@
+package scheduler_triggers_test
+
+import (
+	"context"
+	"testing"
+
+	"service-info-aggregator/internal/repository/scheduler_triggers"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisRepository_TriggersSinceCursor(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	repo := scheduler_triggers.NewRedisRepository(client, "scheduler:triggers")
+	ctx := context.Background()
+
+	ids, cursor, err := repo.Since(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+
+	require.NoError(t, repo.Trigger(ctx, 7))
+	require.NoError(t, repo.Trigger(ctx, 0))
+
+	ids, cursor, err = repo.Since(ctx, cursor)
+	require.NoError(t, err)
+	assert.Equal(t, []int{7, 0}, ids)
+
+	ids, cursor, err = repo.Since(ctx, cursor)
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+
+	// A reader starting now skips the earlier triggers.
+	require.NoError(t, repo.Trigger(ctx, 3))
+	_, latest, err := repo.Since(ctx, "")
+	require.NoError(t, err)
+	ids, _, err = repo.Since(ctx, latest)
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+
+	ids, _, err = repo.Since(ctx, cursor)
+	require.NoError(t, err)
+	assert.Equal(t, []int{3}, ids)
+}
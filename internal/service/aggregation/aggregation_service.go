@@ -2,47 +2,73 @@ package aggregation
 
 import (
 	"context"
-	"encoding/json"
 	"log/slog"
-	"time"
 
-	"service-info-aggregator/internal/messaging/kafka"
+	"service-info-aggregator/internal/messaging"
 	"service-info-aggregator/internal/model/events"
 )
 
 type AggregationService struct {
-	producer *kafka.KafkaProducer
-	topic    string
+	publisher  messaging.Publisher
+	serializer messaging.EventSerializer
+	routes     *messaging.TopicRoutes
+	stateTopic string
+	source     string
 }
 
-func NewAggregationService(p *kafka.KafkaProducer, topic string) *AggregationService {
+func NewAggregationService(p messaging.Publisher, serializer messaging.EventSerializer, routes *messaging.TopicRoutes, stateTopic string, source string) *AggregationService {
 	return &AggregationService{
-		producer: p,
-		topic:    topic,
+		publisher:  p,
+		serializer: serializer,
+		routes:     routes,
+		stateTopic: stateTopic,
+		source:     source,
 	}
 }
 
 func (s *AggregationService) Execute(ctx context.Context, provider Provider, param string) (any, error) {
+	return s.ExecuteFenced(ctx, provider, param, 0)
+}
+
+// ExecuteFenced fetches and publishes like Execute and stamps the event with
+// the scheduler leader's fencing token, so the cache refuses it once a newer
+// leader was elected.
+func (s *AggregationService) ExecuteFenced(ctx context.Context, provider Provider, param string, token int64) (any, error) {
 	result, err := provider.Fetch(ctx, param)
 	if err != nil {
 		return nil, err
 	}
 
-	event := events.GenericUpdatedEvent{
-		Type:      provider.Name(),
-		Key:       param,
-		Payload:   result,
-		Timestamp: time.Now().UTC(),
+	event, err := events.NewCloudEvent(s.source, provider.Name(), param, result)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to build event", "error", err)
+		return result, nil
 	}
+	event.FencingToken = token
 
-	bytes, err := json.Marshal(event)
-	if err != nil {
-		slog.Error("failed to marshal event", "error", err)
-	} else {
-		if err := s.producer.Publish(ctx, s.topic, param, bytes); err != nil {
-			slog.Error("failed to publish event to kafka", "error", err)
-		}
+	s.publish(ctx, s.routes.Topic(event.Type), event, nil)
+
+	// The state topic is log-compacted, so it keeps the latest event for
+	// every type:key pair.
+	if s.stateTopic != "" {
+		s.publish(ctx, s.stateTopic, event, []byte(provider.Name()+":"+param))
 	}
 
 	return result, nil
 }
+
+func (s *AggregationService) publish(ctx context.Context, topic string, event *events.CloudEvent, key []byte) {
+	msg, err := s.serializer.Serialize(ctx, topic, event)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to serialize event", "topic", topic, "error", err)
+		return
+	}
+	if key != nil {
+		msg.Key = key
+	}
+	msg.Headers = append(msg.Headers, messaging.Header{Key: messaging.EventTypeHeader, Value: []byte(event.Type)})
+
+	if err := s.publisher.Publish(ctx, msg); err != nil {
+		slog.ErrorContext(ctx, "failed to publish event", "topic", topic, "error", err)
+	}
+}
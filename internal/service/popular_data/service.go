@@ -2,13 +2,21 @@ package popular_data
 
 import (
 	"context"
+	"log/slog"
 
 	"service-info-aggregator/internal/model/dto"
 	"service-info-aggregator/internal/repository/popular_data"
 )
 
+// Warmer fetches an item into the cache without waiting for its scheduled
+// refresh. Warm only enqueues the fetch and must not block.
+type Warmer interface {
+	Warm(ctx context.Context, item dto.PopularDataDto) error
+}
+
 type PopularDataService struct {
-	Repo popular_data.Repository
+	Repo   popular_data.Repository
+	warmer Warmer
 }
 
 func NewPopularDataService(repo popular_data.Repository) *PopularDataService {
@@ -17,8 +25,21 @@ func NewPopularDataService(repo popular_data.Repository) *PopularDataService {
 	}
 }
 
+// SetWarmer makes Create and Update warm the cache for the saved key.
+func (s *PopularDataService) SetWarmer(w Warmer) {
+	s.warmer = w
+}
+
 func (s *PopularDataService) Create(ctx context.Context, popularDataDto *dto.PopularDataDto) (*dto.PopularDataDto, error) {
-	return s.Repo.Create(ctx, popularDataDto)
+	if _, err := ParseTiming(popularDataDto); err != nil {
+		return nil, err
+	}
+	created, err := s.Repo.Create(ctx, popularDataDto)
+	if err != nil {
+		return nil, err
+	}
+	s.warm(ctx, created)
+	return created, nil
 }
 
 func (s *PopularDataService) GetAll(ctx context.Context) ([]dto.PopularDataDto, error) {
@@ -30,9 +51,28 @@ func (s *PopularDataService) GetById(ctx context.Context, id int) (*dto.PopularD
 }
 
 func (s *PopularDataService) Update(ctx context.Context, id int, inputData *dto.PopularDataDto) (*dto.PopularDataDto, error) {
-	return s.Repo.Update(ctx, id, inputData)
+	if _, err := ParseTiming(inputData); err != nil {
+		return nil, err
+	}
+	updated, err := s.Repo.Update(ctx, id, inputData)
+	if err != nil {
+		return nil, err
+	}
+	s.warm(ctx, updated)
+	return updated, nil
 }
 
 func (s *PopularDataService) Delete(ctx context.Context, id int) error {
 	return s.Repo.Delete(ctx, id)
 }
+
+// warm is best effort: the item is saved either way and its scheduled
+// refresh fills the cache later.
+func (s *PopularDataService) warm(ctx context.Context, item *dto.PopularDataDto) {
+	if s.warmer == nil || item == nil {
+		return
+	}
+	if err := s.warmer.Warm(ctx, *item); err != nil {
+		slog.WarnContext(ctx, "failed to enqueue cache warm-up", "type", item.DataType, "key", item.Key, "error", err)
+	}
+}
@@ -90,3 +90,35 @@ func TestPopularDataService_GetById_Success(t *testing.T) {
 	require.Equal(t, expected, result)
 	repo.AssertExpectations(t)
 }
+
+type recordingWarmer struct {
+	warmed []dto.PopularDataDto
+}
+
+func (w *recordingWarmer) Warm(ctx context.Context, item dto.PopularDataDto) error {
+	w.warmed = append(w.warmed, item)
+	return nil
+}
+
+func TestPopularDataService_CreateAndUpdate_WarmCache(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockPopularDataRepository)
+	warmer := &recordingWarmer{}
+	service := popular_data.NewPopularDataService(repo)
+	service.SetWarmer(warmer)
+
+	input := &dto.PopularDataDto{DataType: "weather", Key: "Moscow"}
+	created := &dto.PopularDataDto{ID: 1, DataType: "weather", Key: "Moscow"}
+	updated := &dto.PopularDataDto{ID: 1, DataType: "weather", Key: "Berlin"}
+
+	repo.On("Create", ctx, input).Return(created, nil)
+	repo.On("Update", ctx, 1, input).Return(updated, nil)
+
+	_, err := service.Create(ctx, input)
+	require.NoError(t, err)
+	_, err = service.Update(ctx, 1, input)
+	require.NoError(t, err)
+
+	require.Equal(t, []dto.PopularDataDto{*created, *updated}, warmer.warmed)
+	repo.AssertExpectations(t)
+}
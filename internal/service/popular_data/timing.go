@@ -0,0 +1,126 @@
+package popular_data
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"service-info-aggregator/internal/model/dto"
+
+	"github.com/robfig/cron/v3"
+)
+
+var ErrInvalidTiming = errors.New("invalid refresh timing")
+
+// maxWindowSearch bounds the search for a cron time inside the active window,
+// so an expression that never fires in the window cannot loop forever.
+const maxWindowSearch = 1000
+
+// Timing is the parsed refresh schedule of a popular data item.
+type Timing struct {
+	cron     cron.Schedule
+	loc      *time.Location
+	window   bool
+	from, to int
+}
+
+func ParseTiming(item *dto.PopularDataDto) (*Timing, error) {
+	t := &Timing{loc: time.UTC}
+
+	if item.Timezone != "" {
+		loc, err := time.LoadLocation(item.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("%w: timezone %q: %v", ErrInvalidTiming, item.Timezone, err)
+		}
+		t.loc = loc
+	}
+
+	if item.CronExpression != "" {
+		schedule, err := cron.ParseStandard(item.CronExpression)
+		if err != nil {
+			return nil, fmt.Errorf("%w: cron expression %q: %v", ErrInvalidTiming, item.CronExpression, err)
+		}
+		t.cron = schedule
+	}
+
+	if item.ActiveFrom == "" && item.ActiveTo == "" {
+		return t, nil
+	}
+	from, err := parseClock(item.ActiveFrom)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseClock(item.ActiveTo)
+	if err != nil {
+		return nil, err
+	}
+	if from == to {
+		return nil, fmt.Errorf("%w: active window %s-%s is empty", ErrInvalidTiming, item.ActiveFrom, item.ActiveTo)
+	}
+	t.window, t.from, t.to = true, from, to
+
+	return t, nil
+}
+
+func parseClock(value string) (int, error) {
+	c, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: time of day %q must be HH:MM", ErrInvalidTiming, value)
+	}
+	return c.Hour()*60 + c.Minute(), nil
+}
+
+// First returns when an item that was never refreshed should run: now for
+// interval items inside their window, otherwise the next allowed time.
+func (t *Timing) First(now time.Time) time.Time {
+	if t.cron != nil {
+		return t.Next(now, 0)
+	}
+	return t.fit(now)
+}
+
+// Next returns the run after the one at after: the next cron time, or after
+// plus interval, moved forward into the active window.
+func (t *Timing) Next(after time.Time, interval time.Duration) time.Time {
+	if t.cron == nil {
+		return t.fit(after.Add(interval))
+	}
+
+	next := t.cron.Next(after.In(t.loc))
+	for range maxWindowSearch {
+		if t.active(next) {
+			return next
+		}
+		next = t.cron.Next(t.windowStart(next).Add(-time.Second))
+	}
+	return next
+}
+
+func (t *Timing) fit(at time.Time) time.Time {
+	if t.active(at) {
+		return at
+	}
+	return t.windowStart(at)
+}
+
+func (t *Timing) active(at time.Time) bool {
+	if !t.window {
+		return true
+	}
+	local := at.In(t.loc)
+	m := local.Hour()*60 + local.Minute()
+	if t.from < t.to {
+		return m >= t.from && m < t.to
+	}
+	return m >= t.from || m < t.to
+}
+
+// windowStart returns the first opening of the window strictly after at.
+func (t *Timing) windowStart(at time.Time) time.Time {
+	local := at.In(t.loc)
+	start := time.Date(local.Year(), local.Month(), local.Day(), t.from/60, t.from%60, 0, 0, t.loc)
+	if !start.After(local) {
+		start = start.AddDate(0, 0, 1)
+	}
+	return start
+}
@@ -0,0 +1,68 @@
+package popular_data_test
+
+import (
+	"testing"
+	"time"
+
+	"service-info-aggregator/internal/model/dto"
+	"service-info-aggregator/internal/service/popular_data"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTiming_CronOnTheHour(t *testing.T) {
+	timing, err := popular_data.ParseTiming(&dto.PopularDataDto{CronExpression: "0 * * * *"})
+	require.NoError(t, err)
+
+	now := time.Date(2026, 3, 2, 10, 17, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 3, 2, 11, 0, 0, 0, time.UTC), timing.First(now))
+	assert.Equal(t, time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC), timing.Next(timing.First(now), time.Minute))
+}
+
+func TestTiming_BusinessHoursWindow(t *testing.T) {
+	timing, err := popular_data.ParseTiming(&dto.PopularDataDto{
+		ActiveFrom: "09:00",
+		ActiveTo:   "18:00",
+		Timezone:   "Europe/Berlin",
+	})
+	require.NoError(t, err)
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	inside := time.Date(2026, 3, 2, 10, 0, 0, 0, berlin)
+	assert.Equal(t, inside, timing.First(inside))
+	assert.Equal(t, inside.Add(30*time.Minute), timing.Next(inside, 30*time.Minute))
+
+	evening := time.Date(2026, 3, 2, 17, 50, 0, 0, berlin)
+	assert.True(t, time.Date(2026, 3, 3, 9, 0, 0, 0, berlin).Equal(timing.Next(evening, 30*time.Minute)))
+}
+
+func TestTiming_CronInsideOvernightWindow(t *testing.T) {
+	timing, err := popular_data.ParseTiming(&dto.PopularDataDto{
+		CronExpression: "0 * * * *",
+		ActiveFrom:     "22:00",
+		ActiveTo:       "02:00",
+	})
+	require.NoError(t, err)
+
+	afternoon := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 3, 2, 22, 0, 0, 0, time.UTC), timing.First(afternoon))
+
+	lastInWindow := time.Date(2026, 3, 3, 1, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 3, 3, 22, 0, 0, 0, time.UTC), timing.Next(lastInWindow, 0))
+}
+
+func TestParseTiming_Invalid(t *testing.T) {
+	for _, item := range []dto.PopularDataDto{
+		{CronExpression: "every hour"},
+		{Timezone: "Mars/Olympus"},
+		{ActiveFrom: "9am", ActiveTo: "18:00"},
+		{ActiveFrom: "09:00"},
+		{ActiveFrom: "09:00", ActiveTo: "09:00"},
+	} {
+		_, err := popular_data.ParseTiming(&item)
+		assert.ErrorIs(t, err, popular_data.ErrInvalidTiming, "%+v", item)
+	}
+}
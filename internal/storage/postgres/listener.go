@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"service-info-aggregator/internal/config"
+
+	"github.com/lib/pq"
+)
+
+// Listen passes the payload of every NOTIFY on channel to handle until ctx is
+// cancelled. The listener reconnects on its own; notifications sent while it
+// was disconnected are lost, so resync is called after every reconnect.
+func Listen(ctx context.Context, cfg *config.PostgresConfig, channel string, handle func(payload string), resync func()) error {
+	listener := pq.NewListener(dsn(cfg), time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		switch ev {
+		case pq.ListenerEventDisconnected, pq.ListenerEventConnectionAttemptFailed:
+			slog.Warn("postgres listener disconnected", "channel", channel, "error", err)
+		case pq.ListenerEventReconnected:
+			slog.Info("postgres listener reconnected", "channel", channel)
+			resync()
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(channel); err != nil {
+		return err
+	}
+	slog.Info("listening for postgres notifications", "channel", channel)
+
+	// A ping now and then notices a dead connection that sees no traffic.
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n := <-listener.Notify:
+			// A nil notification follows a reconnect, which resync covers.
+			if n != nil {
+				handle(n.Extra)
+			}
+		case <-ping.C:
+			go listener.Ping()
+		}
+	}
+}
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS popular_data (
+    id         SERIAL PRIMARY KEY,
+    data_type  TEXT        NOT NULL,
+    key        TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
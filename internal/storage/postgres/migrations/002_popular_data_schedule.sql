@@ -0,0 +1,3 @@
+ALTER TABLE popular_data
+    ADD COLUMN IF NOT EXISTS priority                 INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS refresh_interval_seconds INT NOT NULL DEFAULT 0;
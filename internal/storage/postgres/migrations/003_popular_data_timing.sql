@@ -0,0 +1,5 @@
+ALTER TABLE popular_data
+    ADD COLUMN IF NOT EXISTS cron_expression TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS active_from     TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS active_to       TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS timezone        TEXT NOT NULL DEFAULT '';
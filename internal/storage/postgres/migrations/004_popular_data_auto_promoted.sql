@@ -0,0 +1,2 @@
+ALTER TABLE popular_data
+    ADD COLUMN IF NOT EXISTS auto_promoted BOOLEAN NOT NULL DEFAULT false;
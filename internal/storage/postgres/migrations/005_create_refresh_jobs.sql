@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS refresh_jobs (
+    id           BIGSERIAL PRIMARY KEY,
+    data_type    TEXT NOT NULL,
+    key          TEXT NOT NULL,
+    priority     INT NOT NULL DEFAULT 0,
+    status       TEXT NOT NULL DEFAULT 'pending',
+    attempts     INT NOT NULL DEFAULT 0,
+    run_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    locked_by    TEXT,
+    locked_until TIMESTAMPTZ,
+    last_error   TEXT NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- At most one outstanding job per key, so replicas enqueueing the same
+-- refresh do not duplicate it.
+CREATE UNIQUE INDEX IF NOT EXISTS refresh_jobs_outstanding
+    ON refresh_jobs (data_type, key) WHERE status IN ('pending', 'running');
+
+CREATE INDEX IF NOT EXISTS refresh_jobs_claimable
+    ON refresh_jobs (priority DESC, run_at) WHERE status IN ('pending', 'running');
@@ -0,0 +1,15 @@
+CREATE OR REPLACE FUNCTION notify_popular_data_change() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('popular_data_changes', json_build_object(
+        'op', TG_OP,
+        'id', CASE WHEN TG_OP = 'DELETE' THEN OLD.id ELSE NEW.id END
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS popular_data_notify ON popular_data;
+
+CREATE TRIGGER popular_data_notify
+    AFTER INSERT OR UPDATE OR DELETE ON popular_data
+    FOR EACH ROW EXECUTE FUNCTION notify_popular_data_change();
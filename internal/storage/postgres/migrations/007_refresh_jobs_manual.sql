@@ -0,0 +1,4 @@
+-- Manual jobs come from an admin trigger and run even while their type is
+-- paused.
+ALTER TABLE refresh_jobs
+    ADD COLUMN IF NOT EXISTS manual BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,7 @@
+-- Jobs carry the fencing token of the scheduler leadership that enqueued
+-- them, so the cache refuses their results once a newer leader was elected.
+ALTER TABLE refresh_jobs
+    ADD COLUMN IF NOT EXISTS fencing_token BIGINT NOT NULL DEFAULT 0;
+
+-- Failed jobs are deleted now; the run history and logs keep the failure.
+DELETE FROM refresh_jobs WHERE status = 'failed';
@@ -10,10 +10,7 @@ import (
 )
 
 func NewPostgresConnection(cfg *config.PostgresConfig) (*sql.DB, error) {
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode)
-
-	db, err := sql.Open("postgres", dsn)
+	db, err := sql.Open("postgres", dsn(cfg))
 	if err != nil {
 		return nil, err
 	}
@@ -31,3 +28,8 @@ func NewPostgresConnection(cfg *config.PostgresConfig) (*sql.DB, error) {
 
 	return db, nil
 }
+
+func dsn(cfg *config.PostgresConfig) string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode)
+}
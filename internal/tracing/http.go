@@ -0,0 +1,27 @@
+package tracing
+
+import "net/http"
+
+// Middleware continues the caller's trace, or starts one, and makes sure every
+// request has a request ID that is echoed back in the response.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sc, err := ParseTraceparent(r.Header.Get(TraceparentHeader))
+		if err != nil {
+			sc = NewSpanContext()
+		} else {
+			sc.TraceState = r.Header.Get(TracestateHeader)
+			sc = sc.Child()
+		}
+
+		requestID := r.Header.Get(RequestIDHeader)
+		if requestID == "" {
+			requestID = NewRequestID()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		ctx := ContextWithSpan(r.Context(), sc)
+		ctx = ContextWithRequestID(ctx, requestID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,114 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+	RequestIDHeader   = "X-Request-ID"
+
+	traceparentVersion = "00"
+	flagSampled        = 0x01
+)
+
+// SpanContext is the W3C Trace Context carried between services:
+// https://www.w3.org/TR/trace-context/
+type SpanContext struct {
+	TraceID    string
+	SpanID     string
+	Flags      byte
+	TraceState string
+}
+
+func NewSpanContext() SpanContext {
+	return SpanContext{
+		TraceID: randomHex(16),
+		SpanID:  randomHex(8),
+		Flags:   flagSampled,
+	}
+}
+
+// Child keeps the trace and starts a new span under it.
+func (sc SpanContext) Child() SpanContext {
+	sc.SpanID = randomHex(8)
+	return sc
+}
+
+func (sc SpanContext) Traceparent() string {
+	return fmt.Sprintf("%s-%s-%s-%02x", traceparentVersion, sc.TraceID, sc.SpanID, sc.Flags)
+}
+
+func ParseTraceparent(value string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 {
+		return SpanContext{}, errors.New("traceparent must have four fields")
+	}
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+
+	if len(version) != 2 || version == "ff" || !isHex(version) {
+		return SpanContext{}, fmt.Errorf("invalid traceparent version %q", version)
+	}
+	if version == traceparentVersion && len(parts) != 4 {
+		return SpanContext{}, errors.New("traceparent version 00 must have four fields")
+	}
+	if len(traceID) != 32 || !isHex(traceID) || strings.Trim(traceID, "0") == "" {
+		return SpanContext{}, fmt.Errorf("invalid trace id %q", traceID)
+	}
+	if len(spanID) != 16 || !isHex(spanID) || strings.Trim(spanID, "0") == "" {
+		return SpanContext{}, fmt.Errorf("invalid span id %q", spanID)
+	}
+	if len(flags) != 2 || !isHex(flags) {
+		return SpanContext{}, fmt.Errorf("invalid trace flags %q", flags)
+	}
+
+	b, _ := hex.DecodeString(flags)
+	return SpanContext{TraceID: traceID, SpanID: spanID, Flags: b[0]}, nil
+}
+
+type spanKey struct{}
+
+type requestIDKey struct{}
+
+func ContextWithSpan(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanKey{}, sc)
+}
+
+func SpanFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanKey{}).(SpanContext)
+	return sc, ok
+}
+
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+func NewRequestID() string {
+	return randomHex(16)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}